@@ -0,0 +1,290 @@
+package advisories
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+
+	"github.com/atomicptr/gitlab-composer-integration/composer"
+)
+
+// the format of dates in advisories, it is used by packagist as well
+const dateLayout = "2006-01-02 15:04:05"
+
+// the name of the source of all advisories
+const sourceName = "advisory-database"
+
+// Advisory is a security advisory in the format of the security advisories API of packagist
+type Advisory struct {
+	AdvisoryId       string   `json:"advisoryId"`
+	PackageName      string   `json:"packageName"`
+	RemoteId         string   `json:"remoteId"`
+	Title            string   `json:"title"`
+	Link             string   `json:"link,omitempty"`
+	Cve              string   `json:"cve,omitempty"`
+	AffectedVersions string   `json:"affectedVersions"`
+	Source           string   `json:"source"`
+	ReportedAt       string   `json:"reportedAt"`
+	Severity         string   `json:"severity,omitempty"`
+	Sources          []Source `json:"sources"`
+	// updatedAt is the time the advisory file was modified
+	updatedAt time.Time
+}
+
+type Source struct {
+	Name     string `json:"name"`
+	RemoteId string `json:"remoteId"`
+}
+
+// Database contains all advisories of a directory, indexed by package name
+type Database struct {
+	advisories map[string][]*Advisory
+	count      int
+	// updatedAt is the time the most recently modified advisory file was modified
+	updatedAt time.Time
+}
+
+// rawAdvisory is an advisory file, it is either in the format of the FriendsOfPHP security advisories database
+// (with branches) or contains the affected versions as single constraint
+type rawAdvisory struct {
+	Title     string               `yaml:"title"`
+	Link      string               `yaml:"link"`
+	Cve       string               `yaml:"cve"`
+	Reference string               `yaml:"reference"`
+	Branches  map[string]rawBranch `yaml:"branches"`
+
+	AdvisoryId       string `yaml:"advisoryId"`
+	PackageName      string `yaml:"packageName"`
+	AffectedVersions string `yaml:"affectedVersions"`
+	ReportedAt       string `yaml:"reportedAt"`
+	Severity         string `yaml:"severity"`
+}
+
+type rawBranch struct {
+	Time     string   `yaml:"time"`
+	Versions []string `yaml:"versions"`
+}
+
+// Load reads all advisories (*.yaml, *.yml and *.json files) of the directory and its sub directories. Files which
+// do not name their package are expected at <vendor>/<package>/<advisory>.yaml, like in the FriendsOfPHP database.
+func Load(path string) (*Database, error) {
+	db := &Database{advisories: make(map[string][]*Advisory)}
+
+	err := filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			// skips e.g. the .git directory of a mirrored database
+			if file != path && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		switch strings.ToLower(filepath.Ext(file)) {
+		case ".yaml", ".yml", ".json":
+		default:
+			return nil
+		}
+
+		relativePath, err := filepath.Rel(path, file)
+		if err != nil {
+			return err
+		}
+
+		advisory, err := readAdvisory(file, filepath.ToSlash(relativePath), info.ModTime())
+		if err != nil {
+			return errors.Wrapf(err, "invalid advisory %s", file)
+		}
+
+		db.advisories[advisory.PackageName] = append(db.advisories[advisory.PackageName], advisory)
+		db.count++
+		if advisory.updatedAt.After(db.updatedAt) {
+			db.updatedAt = advisory.updatedAt
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "could not load advisory database")
+	}
+
+	for _, advisories := range db.advisories {
+		sort.Slice(advisories, func(i, j int) bool {
+			return advisories[i].AdvisoryId < advisories[j].AdvisoryId
+		})
+	}
+
+	return db, nil
+}
+
+func readAdvisory(file, relativePath string, modified time.Time) (*Advisory, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw rawAdvisory
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	advisory := &Advisory{
+		AdvisoryId:       raw.AdvisoryId,
+		PackageName:      raw.PackageName,
+		RemoteId:         relativePath,
+		Title:            raw.Title,
+		Link:             raw.Link,
+		Cve:              raw.Cve,
+		AffectedVersions: raw.AffectedVersions,
+		Source:           sourceName,
+		Severity:         raw.Severity,
+		Sources:          []Source{{Name: sourceName, RemoteId: relativePath}},
+		updatedAt:        modified,
+	}
+
+	if advisory.AdvisoryId == "" {
+		hash := sha256.Sum256([]byte(relativePath))
+		advisory.AdvisoryId = "GCI-" + hex.EncodeToString(hash[:8])
+	}
+
+	if advisory.PackageName == "" {
+		advisory.PackageName = packageNameFromReference(raw.Reference, relativePath)
+	}
+
+	if advisory.PackageName == "" {
+		return nil, errors.New("the package is unknown, use packageName or reference")
+	}
+
+	if advisory.Title == "" {
+		return nil, errors.New("the title is missing")
+	}
+
+	reportedAt, err := parseDate(raw.ReportedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(raw.Branches) > 0 {
+		var branchReportedAt time.Time
+		advisory.AffectedVersions, branchReportedAt, err = mergeBranches(raw.Branches)
+		if err != nil {
+			return nil, err
+		}
+
+		if reportedAt.IsZero() {
+			reportedAt = branchReportedAt
+		}
+	}
+
+	if advisory.AffectedVersions == "" {
+		return nil, errors.New("the affected versions are missing, use affectedVersions or branches")
+	}
+
+	if _, err := composer.ParseConstraint(advisory.AffectedVersions); err != nil {
+		return nil, err
+	}
+
+	if reportedAt.IsZero() {
+		reportedAt = modified
+	}
+	advisory.ReportedAt = reportedAt.UTC().Format(dateLayout)
+
+	return advisory, nil
+}
+
+// mergeBranches returns the affected versions of all branches as single constraint and the time the first branch
+// was reported
+func mergeBranches(branches map[string]rawBranch) (string, time.Time, error) {
+	names := make([]string, 0, len(branches))
+	for name := range branches {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var constraints []string
+	var reportedAt time.Time
+
+	for _, name := range names {
+		branch := branches[name]
+		if len(branch.Versions) == 0 {
+			return "", time.Time{}, fmt.Errorf("branch %s has no versions", name)
+		}
+
+		constraints = append(constraints, strings.Join(branch.Versions, ","))
+
+		branchTime, err := parseDate(branch.Time)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+
+		if !branchTime.IsZero() && (reportedAt.IsZero() || branchTime.Before(reportedAt)) {
+			reportedAt = branchTime
+		}
+	}
+
+	return strings.Join(constraints, "|"), reportedAt, nil
+}
+
+func packageNameFromReference(reference, relativePath string) string {
+	if strings.HasPrefix(reference, "composer://") {
+		return strings.TrimPrefix(reference, "composer://")
+	}
+
+	parts := strings.Split(relativePath, "/")
+	if len(parts) < 3 {
+		return ""
+	}
+
+	return parts[len(parts)-3] + "/" + parts[len(parts)-2]
+}
+
+func parseDate(date string) (time.Time, error) {
+	if date == "" {
+		return time.Time{}, nil
+	}
+
+	for _, layout := range []string{dateLayout, time.RFC3339, "2006-01-02"} {
+		if parsed, err := time.Parse(layout, date); err == nil {
+			return parsed, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid date \"%s\"", date)
+}
+
+// Count returns the amount of advisories in the database
+func (db *Database) Count() int {
+	return db.count
+}
+
+// UpdatedAt returns the time the most recently modified advisory was modified at
+func (db *Database) UpdatedAt() time.Time {
+	return db.updatedAt
+}
+
+// ForPackages returns the advisories of the given packages which have been updated since the given time (all if it
+// is zero), packages without advisories are left out
+func (db *Database) ForPackages(packageNames []string, updatedSince time.Time) map[string][]*Advisory {
+	result := make(map[string][]*Advisory)
+
+	for _, name := range packageNames {
+		for _, advisory := range db.advisories[name] {
+			if updatedSince.IsZero() || !advisory.updatedAt.Before(updatedSince) {
+				result[name] = append(result[name], advisory)
+			}
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,128 @@
+package advisories
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const friendsOfPhpAdvisory = `title:     "CVE-2019-10909: Escape validation messages in the PHP templating engine"
+link:      https://symfony.com/cve-2019-10909
+cve:       CVE-2019-10909
+branches:
+    4.2.x:
+        time:     2019-04-16 22:00:00
+        versions: ['>=4.2.0', '<4.2.7']
+    3.4.x:
+        time:     2019-04-17 10:00:00
+        versions: ['>=3.4.0', '<3.4.26']
+reference: composer://symfony/form
+`
+
+const jsonAdvisory = `{
+	"advisoryId": "ACME-2020-1",
+	"packageName": "acme/logger",
+	"title": "Log injection",
+	"affectedVersions": ">=1.0,<1.2.3",
+	"reportedAt": "2020-03-01 12:00:00",
+	"severity": "high"
+}`
+
+func writeTestAdvisory(t *testing.T, dir, path, content string) {
+	file := filepath.Join(dir, filepath.FromSlash(path))
+	assert.Nil(t, os.MkdirAll(filepath.Dir(file), 0755))
+	assert.Nil(t, ioutil.WriteFile(file, []byte(content), 0644))
+}
+
+func createTestDatabase(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "gci-advisories")
+	assert.Nil(t, err)
+
+	writeTestAdvisory(t, dir, "symfony/form/CVE-2019-10909.yaml", friendsOfPhpAdvisory)
+	writeTestAdvisory(t, dir, "internal/acme-logger.json", jsonAdvisory)
+	writeTestAdvisory(t, dir, "acme/http-client/2020-04-01.yml", "title: SSRF\naffectedVersions: <2.0.1\n")
+	writeTestAdvisory(t, dir, ".git/config.yaml", "this is: [no advisory")
+	writeTestAdvisory(t, dir, "README.md", "# Advisories")
+
+	return dir, func() {
+		_ = os.RemoveAll(dir)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir, cleanup := createTestDatabase(t)
+	defer cleanup()
+
+	db, err := Load(dir)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 3, db.Count())
+
+	advisories := db.ForPackages([]string{"symfony/form", "acme/logger", "acme/http-client", "acme/unknown"}, time.Time{})
+	assert.Len(t, advisories, 3)
+
+	form := advisories["symfony/form"][0]
+	assert.EqualValues(t, "symfony/form", form.PackageName)
+	assert.EqualValues(t, "CVE-2019-10909", form.Cve)
+	assert.EqualValues(t, ">=3.4.0,<3.4.26|>=4.2.0,<4.2.7", form.AffectedVersions)
+	assert.EqualValues(t, "2019-04-16 22:00:00", form.ReportedAt)
+	assert.EqualValues(t, "symfony/form/CVE-2019-10909.yaml", form.RemoteId)
+	assert.Regexp(t, "^GCI-[0-9a-f]{16}$", form.AdvisoryId)
+
+	logger := advisories["acme/logger"][0]
+	assert.EqualValues(t, "ACME-2020-1", logger.AdvisoryId)
+	assert.EqualValues(t, ">=1.0,<1.2.3", logger.AffectedVersions)
+	assert.EqualValues(t, "high", logger.Severity)
+
+	// the package name is taken from the path, the reported date from the file
+	client := advisories["acme/http-client"][0]
+	assert.EqualValues(t, "SSRF", client.Title)
+	assert.NotEmpty(t, client.ReportedAt)
+}
+
+func TestForPackagesUpdatedSince(t *testing.T) {
+	dir, cleanup := createTestDatabase(t)
+	defer cleanup()
+
+	old := time.Now().Add(-48 * time.Hour)
+	assert.Nil(t, os.Chtimes(filepath.Join(dir, "internal", "acme-logger.json"), old, old))
+
+	db, err := Load(dir)
+	assert.Nil(t, err)
+
+	advisories := db.ForPackages([]string{"acme/logger", "acme/http-client"}, time.Now().Add(-time.Hour))
+	assert.Len(t, advisories, 1)
+	assert.Len(t, advisories["acme/http-client"], 1)
+}
+
+func TestLoadInvalidAdvisories(t *testing.T) {
+	invalid := map[string]string{
+		"acme/logger/invalid-yaml.yaml":    "title: [",
+		"acme/logger/missing-title.yaml":   "affectedVersions: <1.0",
+		"acme/logger/missing-versions.yml": "title: Missing versions",
+		"acme/logger/invalid-versions.yml": "title: Invalid\naffectedVersions: foo",
+		"acme/logger/invalid-date.yml":     "title: Invalid\naffectedVersions: <1.0\nreportedAt: yesterday",
+		"acme/logger/empty-branch.yml":     "title: Invalid\nbranches:\n  1.x:\n    time: ~\n    versions: []",
+		"missing-package.yml":              "title: Unknown\naffectedVersions: <1.0",
+	}
+
+	for path, content := range invalid {
+		dir, err := ioutil.TempDir("", "gci-advisories")
+		assert.Nil(t, err)
+
+		writeTestAdvisory(t, dir, path, content)
+
+		_, err = Load(dir)
+		assert.NotNil(t, err, path)
+
+		_ = os.RemoveAll(dir)
+	}
+}
+
+func TestLoadMissingDirectory(t *testing.T) {
+	_, err := Load("/does/not/exist")
+	assert.NotNil(t, err)
+}
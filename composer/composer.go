@@ -9,6 +9,15 @@ type Repository struct {
 	NotifyBatch  string              `json:"notify-batch"`
 	ProvidersUrl string              `json:"providers-url"`
 	Providers    map[string]Provider `json:"providers"`
+	SearchUrl    string              `json:"search,omitempty"`
+	ListUrl      string              `json:"list,omitempty"`
+	// SecurityAdvisories tells composer (2.4+) where to query security advisories for composer audit
+	SecurityAdvisories *SecurityAdvisories `json:"security-advisories,omitempty"`
+}
+
+type SecurityAdvisories struct {
+	Metadata bool   `json:"metadata"`
+	ApiUrl   string `json:"api-url"`
 }
 
 type Provider struct {
@@ -22,3 +31,25 @@ type ProviderRepository struct {
 func (r *Repository) ToJson() ([]byte, error) {
 	return json.Marshal(r)
 }
+
+// SearchResults is the response of the search url, it is used by composer search
+type SearchResults struct {
+	Results []SearchResult `json:"results"`
+	Total   int            `json:"total"`
+}
+
+type SearchResult struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Url         string `json:"url"`
+	Repository  string `json:"repository,omitempty"`
+	Downloads   uint64 `json:"downloads"`
+	Favers      int    `json:"favers"`
+	// Abandoned is true or the name of the replacement package
+	Abandoned interface{} `json:"abandoned,omitempty"`
+}
+
+// PackageList is the response of the list url, it is used by composer show --all
+type PackageList struct {
+	PackageNames []string `json:"packageNames"`
+}
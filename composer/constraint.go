@@ -0,0 +1,445 @@
+package composer
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// stabilities ordered from the least to the most stable, patch releases come after the release itself
+const (
+	stabilityDev = iota
+	stabilityAlpha
+	stabilityBeta
+	stabilityRC
+	stabilityStable
+	stabilityPatch
+)
+
+var stabilities = map[string]int{
+	"dev":    stabilityDev,
+	"alpha":  stabilityAlpha,
+	"a":      stabilityAlpha,
+	"beta":   stabilityBeta,
+	"b":      stabilityBeta,
+	"rc":     stabilityRC,
+	"stable": stabilityStable,
+	"patch":  stabilityPatch,
+	"pl":     stabilityPatch,
+	"p":      stabilityPatch,
+}
+
+// versions of composer branches like 1.x-dev are normalized with this number for the x
+const branchAliasNumber = 9999999
+
+var (
+	versionPattern = regexp.MustCompile(
+		`(?i)^v?(\d+)(?:\.(\d+))?(?:\.(\d+))?(?:\.(\d+))?` +
+			`(?:[._-]?(stable|beta|b|rc|alpha|a|patch|pl|p)((?:[.-]?\d+)*))?([.-]?dev)?$`,
+	)
+	branchAliasPattern = regexp.MustCompile(`(?i)^v?(\d+)(?:\.(\d+))?(?:\.(\d+))?\.x-dev$`)
+	wildcardPattern    = regexp.MustCompile(`(?i)^v?(\d+)(?:\.(\d+|[x*]))?(?:\.(\d+|[x*]))?(?:\.(\d+|[x*]))?$`)
+	hyphenRangePattern = regexp.MustCompile(`^(\S+)\s+-\s+(\S+)$`)
+	operatorPattern    = regexp.MustCompile(`^(<>|!=|>=|<=|==|=|<|>)?(.+)$`)
+	operatorSpaces     = regexp.MustCompile(`(<>|!=|>=|<=|==|=|<|>|\^|~)\s+`)
+	orPattern          = regexp.MustCompile(`\s*\|\|?\s*`)
+	stabilityFlag      = regexp.MustCompile(`(?i)@(stable|rc|beta|alpha|dev)$`)
+)
+
+// Version is a normalized composer version, e.g. the name of a tag like v1.2.3 or a branch like dev-master
+type Version struct {
+	original        string
+	parts           [4]int
+	stability       int
+	stabilityNumber int
+	// branch is set for development branches like dev-master, they are not comparable with numbered versions
+	branch string
+}
+
+// ParseVersion normalizes the given version the same way composer does
+func ParseVersion(version string) (Version, error) {
+	version = strings.TrimSpace(version)
+	parsed := Version{original: version}
+
+	// build metadata is not taken into account
+	normalized := strings.SplitN(version, "+", 2)[0]
+
+	if strings.HasPrefix(strings.ToLower(normalized), "dev-") {
+		parsed.branch = normalized
+		parsed.stability = stabilityDev
+		return parsed, nil
+	}
+
+	if matches := branchAliasPattern.FindStringSubmatch(normalized); matches != nil {
+		for i := range parsed.parts {
+			parsed.parts[i] = branchAliasNumber
+		}
+		for i, part := range matches[1:4] {
+			if part != "" {
+				parsed.parts[i], _ = strconv.Atoi(part)
+			}
+		}
+		parsed.stability = stabilityDev
+		return parsed, nil
+	}
+
+	parsed, _, err := parseNumberedVersion(version, normalized)
+	return parsed, err
+}
+
+// parseNumberedVersion returns the version and the amount of numbers it consists of, e.g. 2 for 1.2
+func parseNumberedVersion(original, version string) (Version, int, error) {
+	matches := versionPattern.FindStringSubmatch(version)
+	if matches == nil {
+		return Version{}, 0, fmt.Errorf("invalid version \"%s\"", original)
+	}
+
+	parsed := Version{original: original, stability: stabilityStable}
+	count := 0
+
+	for i, part := range matches[1:5] {
+		if part == "" {
+			break
+		}
+		number, err := strconv.Atoi(part)
+		if err != nil {
+			return Version{}, 0, fmt.Errorf("invalid version \"%s\"", original)
+		}
+		parsed.parts[i] = number
+		count++
+	}
+
+	if matches[5] != "" {
+		parsed.stability = stabilities[strings.ToLower(matches[5])]
+		number := strings.TrimLeft(matches[6], ".-")
+		if index := strings.IndexAny(number, ".-"); index >= 0 {
+			number = number[:index]
+		}
+		parsed.stabilityNumber, _ = strconv.Atoi(number)
+	}
+
+	if matches[7] != "" {
+		parsed.stability = stabilityDev
+	}
+
+	return parsed, count, nil
+}
+
+func (v Version) String() string {
+	return v.original
+}
+
+// IsBranch returns true for development branches like dev-master
+func (v Version) IsBranch() bool {
+	return v.branch != ""
+}
+
+// IsStable returns true for versions which are no development versions and no pre-releases
+func (v Version) IsStable() bool {
+	return v.branch == "" && v.stability >= stabilityStable
+}
+
+// Compare returns -1 if the version is lower than the other one, 1 if it is greater and 0 if they are equal.
+// Branches are lower than all numbered versions.
+func (v Version) Compare(other Version) int {
+	if v.IsBranch() || other.IsBranch() {
+		switch {
+		case v.IsBranch() && !other.IsBranch():
+			return -1
+		case !v.IsBranch() && other.IsBranch():
+			return 1
+		}
+		return strings.Compare(v.branch, other.branch)
+	}
+
+	for i := range v.parts {
+		if result := compareInts(v.parts[i], other.parts[i]); result != 0 {
+			return result
+		}
+	}
+
+	if result := compareInts(v.stability, other.stability); result != 0 {
+		return result
+	}
+
+	return compareInts(v.stabilityNumber, other.stabilityNumber)
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// Constraint is a composer version constraint like "^1.2 || ~2.0.3"
+type Constraint struct {
+	text string
+	// the constraint matches if all comparisons of any alternative match
+	alternatives [][]comparison
+}
+
+type comparison struct {
+	operator string
+	version  Version
+}
+
+// ParseConstraint parses version constraints the same way composer does, stability flags like @dev and
+// aliases (e.g. "dev-feature as 1.0.0") are ignored
+func ParseConstraint(text string) (*Constraint, error) {
+	constraint := &Constraint{text: text}
+
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
+		return nil, fmt.Errorf("empty version constraint")
+	}
+
+	for _, alternative := range orPattern.Split(trimmed, -1) {
+		comparisons, err := parseAndConstraint(alternative)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version constraint \"%s\": %s", text, err)
+		}
+		constraint.alternatives = append(constraint.alternatives, comparisons)
+	}
+
+	return constraint, nil
+}
+
+func parseAndConstraint(text string) ([]comparison, error) {
+	if index := strings.Index(text, " as "); index >= 0 {
+		text = text[:index]
+	}
+
+	text = strings.TrimSpace(text)
+
+	if matches := hyphenRangePattern.FindStringSubmatch(text); matches != nil {
+		return parseHyphenRange(matches[1], matches[2])
+	}
+
+	text = operatorSpaces.ReplaceAllString(strings.Replace(text, ",", " ", -1), "$1")
+
+	parts := strings.Fields(text)
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("empty alternative")
+	}
+
+	// an empty list of comparisons matches every version
+	comparisons := []comparison{}
+	for _, part := range parts {
+		parsed, err := parseSingleConstraint(part)
+		if err != nil {
+			return nil, err
+		}
+		comparisons = append(comparisons, parsed...)
+	}
+
+	return comparisons, nil
+}
+
+func parseSingleConstraint(text string) ([]comparison, error) {
+	text = stabilityFlag.ReplaceAllString(text, "")
+	if text == "" {
+		// a plain stability flag like @dev allows every version
+		return nil, nil
+	}
+
+	if text == "*" || strings.EqualFold(text, "x") || strings.HasPrefix(text, "*.") {
+		return nil, nil
+	}
+
+	switch text[0] {
+	case '^':
+		return parseCaretConstraint(text[1:])
+	case '~':
+		return parseTildeConstraint(text[1:])
+	}
+
+	if matches := wildcardPattern.FindStringSubmatch(text); matches != nil && strings.ContainsAny(text, "xX*") {
+		return parseWildcardConstraint(matches)
+	}
+
+	matches := operatorPattern.FindStringSubmatch(text)
+	operator := matches[1]
+	switch operator {
+	case "", "=":
+		operator = "=="
+	case "<>":
+		operator = "!="
+	}
+
+	version, err := ParseVersion(matches[2])
+	if err != nil {
+		return nil, err
+	}
+
+	// "<2.0" excludes the pre-releases of 2.0 and ">=1.0" includes them, just like composer does
+	if (operator == "<" || operator == ">=") && !version.IsBranch() && !hasStability(matches[2]) {
+		version.stability = stabilityDev
+	}
+
+	return []comparison{{operator: operator, version: version}}, nil
+}
+
+// parseCaretConstraint allows all versions up to the next significant release, e.g. ^1.2.3 is >=1.2.3 <2.0.0
+// and ^0.3 is >=0.3.0 <0.4.0
+func parseCaretConstraint(text string) ([]comparison, error) {
+	lower, count, err := parseNumberedVersion(text, text)
+	if err != nil {
+		return nil, err
+	}
+
+	position := 0
+	switch {
+	case lower.parts[0] != 0 || count < 2:
+		position = 0
+	case lower.parts[1] != 0 || count < 3:
+		position = 1
+	default:
+		position = 2
+	}
+
+	return createRange(lower, text, bump(lower, position)), nil
+}
+
+// parseTildeConstraint allows the last given number to increase, e.g. ~1.2 is >=1.2 <2.0 and ~1.2.3 is
+// >=1.2.3 <1.3.0
+func parseTildeConstraint(text string) ([]comparison, error) {
+	lower, count, err := parseNumberedVersion(text, text)
+	if err != nil {
+		return nil, err
+	}
+
+	position := count - 2
+	if position < 0 {
+		position = 0
+	}
+
+	return createRange(lower, text, bump(lower, position)), nil
+}
+
+// parseWildcardConstraint allows all versions matching the given numbers, e.g. 1.2.* is >=1.2.0 <1.3.0
+func parseWildcardConstraint(matches []string) ([]comparison, error) {
+	lower := Version{original: matches[0], stability: stabilityDev}
+
+	position := 0
+	for i, part := range matches[1:5] {
+		number, err := strconv.Atoi(part)
+		if err != nil {
+			position = i - 1
+			break
+		}
+		lower.parts[i] = number
+	}
+
+	return []comparison{
+		{operator: ">=", version: lower},
+		{operator: "<", version: bump(lower, position)},
+	}, nil
+}
+
+// parseHyphenRange allows all versions between the given ones, if the upper version is partial (e.g. 2.1) all of
+// its patch releases are included
+func parseHyphenRange(from, to string) ([]comparison, error) {
+	lower, _, err := parseNumberedVersion(from, from)
+	if err != nil {
+		return nil, err
+	}
+	if !hasStability(from) {
+		lower.stability = stabilityDev
+	}
+
+	upper, count, err := parseNumberedVersion(to, to)
+	if err != nil {
+		return nil, err
+	}
+
+	if count >= 3 || hasStability(to) {
+		return []comparison{{operator: ">=", version: lower}, {operator: "<=", version: upper}}, nil
+	}
+
+	return []comparison{{operator: ">=", version: lower}, {operator: "<", version: bump(upper, count-1)}}, nil
+}
+
+func createRange(lower Version, text string, upper Version) []comparison {
+	if !hasStability(text) {
+		lower.stability = stabilityDev
+	}
+
+	return []comparison{{operator: ">=", version: lower}, {operator: "<", version: upper}}
+}
+
+// bump increases the number at the given position, resets the following ones and marks the version as the
+// development version (the lowest possible version) of the resulting release
+func bump(version Version, position int) Version {
+	bumped := Version{stability: stabilityDev}
+	copy(bumped.parts[:position], version.parts[:position])
+	bumped.parts[position] = version.parts[position] + 1
+	bumped.original = fmt.Sprintf("%d.%d.%d.%d-dev", bumped.parts[0], bumped.parts[1], bumped.parts[2], bumped.parts[3])
+	return bumped
+}
+
+func hasStability(version string) bool {
+	matches := versionPattern.FindStringSubmatch(strings.SplitN(version, "+", 2)[0])
+	return matches != nil && (matches[5] != "" || matches[7] != "")
+}
+
+func (c *Constraint) String() string {
+	return c.text
+}
+
+// Matches checks if the given version satisfies the constraint
+func (c *Constraint) Matches(version Version) bool {
+	for _, comparisons := range c.alternatives {
+		if matchesAll(comparisons, version) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesAll(comparisons []comparison, version Version) bool {
+	for _, comparison := range comparisons {
+		if !comparison.matches(version) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c comparison) matches(version Version) bool {
+	// branches can only be required by their name
+	if version.IsBranch() || c.version.IsBranch() {
+		equal := version.IsBranch() && c.version.IsBranch() && strings.EqualFold(version.branch, c.version.branch)
+
+		switch c.operator {
+		case "==":
+			return equal
+		case "!=":
+			return !equal
+		}
+		return false
+	}
+
+	result := version.Compare(c.version)
+
+	switch c.operator {
+	case "==":
+		return result == 0
+	case "!=":
+		return result != 0
+	case "<":
+		return result < 0
+	case "<=":
+		return result <= 0
+	case ">":
+		return result > 0
+	case ">=":
+		return result >= 0
+	}
+
+	return false
+}
@@ -0,0 +1,136 @@
+package composer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseVersion(t *testing.T) {
+	invalid := []string{"", "latest", "1.2.3.4.5", "1.0-foo", "^1.0"}
+	for _, version := range invalid {
+		_, err := ParseVersion(version)
+		assert.NotNil(t, err, version)
+	}
+
+	version, err := ParseVersion("v1.2.3")
+	assert.Nil(t, err)
+	assert.EqualValues(t, "v1.2.3", version.String())
+	assert.EqualValues(t, [4]int{1, 2, 3, 0}, version.parts)
+	assert.True(t, version.IsStable())
+	assert.False(t, version.IsBranch())
+
+	version, err = ParseVersion("2.0.0-RC2")
+	assert.Nil(t, err)
+	assert.EqualValues(t, stabilityRC, version.stability)
+	assert.EqualValues(t, 2, version.stabilityNumber)
+	assert.False(t, version.IsStable())
+
+	version, err = ParseVersion("dev-master")
+	assert.Nil(t, err)
+	assert.True(t, version.IsBranch())
+	assert.False(t, version.IsStable())
+
+	version, err = ParseVersion("1.x-dev")
+	assert.Nil(t, err)
+	assert.EqualValues(t, [4]int{1, branchAliasNumber, branchAliasNumber, branchAliasNumber}, version.parts)
+	assert.EqualValues(t, stabilityDev, version.stability)
+}
+
+func TestVersionCompare(t *testing.T) {
+	ordered := []string{
+		"dev-master",
+		"0.9",
+		"1.0.0-dev",
+		"1.0.0-alpha1",
+		"1.0.0-alpha2",
+		"1.0.0-beta",
+		"1.0.0-RC1",
+		"v1.0.0",
+		"1.0.0-patch1",
+		"1.0.1",
+		"1.0.10",
+		"1.1",
+		"2.0.0+build.1",
+	}
+
+	for i := 0; i < len(ordered)-1; i++ {
+		lower, err := ParseVersion(ordered[i])
+		assert.Nil(t, err)
+		higher, err := ParseVersion(ordered[i+1])
+		assert.Nil(t, err)
+
+		assert.EqualValues(t, -1, lower.Compare(higher), "%s < %s", ordered[i], ordered[i+1])
+		assert.EqualValues(t, 1, higher.Compare(lower), "%s > %s", ordered[i+1], ordered[i])
+	}
+
+	a, _ := ParseVersion("v1.0")
+	b, _ := ParseVersion("1.0.0.0")
+	assert.EqualValues(t, 0, a.Compare(b))
+}
+
+func TestParseConstraintInvalid(t *testing.T) {
+	invalid := []string{"", "  ", "^foo", "~", ">=", "1.0 - foo", "foo"}
+	for _, constraint := range invalid {
+		_, err := ParseConstraint(constraint)
+		assert.NotNil(t, err, constraint)
+	}
+}
+
+func TestConstraintMatches(t *testing.T) {
+	tests := map[string]struct {
+		matching    []string
+		notMatching []string
+	}{
+		"*":               {[]string{"1.0.0", "0.1", "2.0.0-beta", "dev-master"}, nil},
+		"@dev":            {[]string{"1.0.0"}, nil},
+		"1.2.3":           {[]string{"1.2.3", "v1.2.3", "1.2.3.0"}, []string{"1.2.4", "1.2.3-beta"}},
+		"=1.2.3":          {[]string{"1.2.3"}, []string{"1.2.4"}},
+		"!=1.2.3":         {[]string{"1.2.4"}, []string{"1.2.3"}},
+		">=1.2":           {[]string{"1.2.0", "1.2.0-beta", "3.0"}, []string{"1.1.9"}},
+		">1.2":            {[]string{"1.2.1"}, []string{"1.2.0"}},
+		"<2.0":            {[]string{"1.9.9"}, []string{"2.0.0", "2.0.0-beta"}},
+		"<=2.0":           {[]string{"2.0.0", "2.0.0-beta"}, []string{"2.0.1"}},
+		">=1.0 <2.0":      {[]string{"1.0", "1.5.3"}, []string{"0.9", "2.0"}},
+		">= 1.0, < 2.0":   {[]string{"1.5.3"}, []string{"2.0"}},
+		"^1.2.3":          {[]string{"1.2.3", "1.9.0", "1.2.3-beta"}, []string{"1.2.2", "2.0.0", "2.0.0-alpha"}},
+		"^0.3":            {[]string{"0.3.0", "0.3.9"}, []string{"0.4.0", "0.2.9"}},
+		"^0.0.3":          {[]string{"0.0.3"}, []string{"0.0.4"}},
+		"^1":              {[]string{"1.0", "1.99"}, []string{"2.0"}},
+		"^ 1.2":           {[]string{"1.3"}, []string{"2.0"}},
+		"~1.2":            {[]string{"1.2.0", "1.9"}, []string{"2.0", "1.1"}},
+		"~1.2.3":          {[]string{"1.2.3", "1.2.9"}, []string{"1.3.0"}},
+		"~1":              {[]string{"1.0", "1.9"}, []string{"2.0"}},
+		"1.2.*":           {[]string{"1.2.0", "1.2.9", "1.2.0-beta"}, []string{"1.3.0", "1.1.9"}},
+		"1.x":             {[]string{"1.0", "1.9.9"}, []string{"2.0.0"}},
+		"1.0 - 2.0":       {[]string{"1.0.0", "2.0.9"}, []string{"2.1.0", "0.9"}},
+		"1.0.0 - 2.1.0":   {[]string{"2.1.0"}, []string{"2.1.1"}},
+		"^1.0 || ^2.0":    {[]string{"1.5", "2.5"}, []string{"3.0", "0.9"}},
+		"^1.0 | ^3.0":     {[]string{"1.5", "3.5"}, []string{"2.0"}},
+		"^1.0@dev":        {[]string{"1.5"}, []string{"2.0"}},
+		"dev-master":      {[]string{"dev-master"}, []string{"1.0.0", "dev-develop"}},
+		"dev-master as 1": {[]string{"dev-master"}, []string{"1.0.0"}},
+		"1.x-dev":         {[]string{"1.x-dev"}, []string{"1.0.0"}},
+	}
+
+	for text, test := range tests {
+		constraint, err := ParseConstraint(text)
+		if !assert.Nil(t, err, text) {
+			continue
+		}
+
+		assert.EqualValues(t, text, constraint.String())
+
+		for _, version := range test.matching {
+			parsed, err := ParseVersion(version)
+			assert.Nil(t, err)
+			assert.True(t, constraint.Matches(parsed), "%s should match %s", text, version)
+		}
+
+		for _, version := range test.notMatching {
+			parsed, err := ParseVersion(version)
+			assert.Nil(t, err)
+			assert.False(t, constraint.Matches(parsed), "%s should not match %s", text, version)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+//go:build ignore
+// +build ignore
+
+// gen_schema downloads the official Composer schema and writes it to composer_schema.go, run it with
+// "go generate ./composer" after changing the pinned Composer version.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// the Composer release the schema is taken from
+const composerVersion = "1.10.5"
+
+func main() {
+	source := flag.String(
+		"source",
+		fmt.Sprintf("https://raw.githubusercontent.com/composer/composer/%s/res/composer-schema.json", composerVersion),
+		"url of the schema",
+	)
+	output := flag.String("output", "composer_schema.go", "file the schema is written to")
+	flag.Parse()
+
+	schema, err := download(*source)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if !json.Valid(schema) {
+		log.Fatalf("%s is not valid JSON", *source)
+	}
+
+	var buffer bytes.Buffer
+	_, _ = fmt.Fprintf(&buffer, "// Code generated by gen_schema.go; DO NOT EDIT.\n\n")
+	_, _ = fmt.Fprintf(&buffer, "package composer\n\n")
+	_, _ = fmt.Fprintf(&buffer, "// composerJsonSchemaVersion is the Composer release the schema was taken from\n")
+	_, _ = fmt.Fprintf(&buffer, "const composerJsonSchemaVersion = %q\n\n", composerVersion)
+	_, _ = fmt.Fprintf(&buffer, "// composerJsonSchemaSource is res/composer-schema.json of composer/composer\n")
+	_, _ = fmt.Fprintf(
+		&buffer,
+		"const composerJsonSchemaSource = `%s`\n",
+		escapeBackticks(string(bytes.TrimSpace(schema))),
+	)
+
+	if err := ioutil.WriteFile(*output, buffer.Bytes(), 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func download(source string) ([]byte, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		return ioutil.ReadFile(source)
+	}
+
+	response, err := http.Get(source)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("could not download %s: %s", source, response.Status)
+	}
+
+	return ioutil.ReadAll(response.Body)
+}
+
+// escapeBackticks allows the schema to be used in a raw string literal
+func escapeBackticks(schema string) string {
+	return strings.ReplaceAll(schema, "`", "` + \"`\" + `")
+}
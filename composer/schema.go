@@ -0,0 +1,115 @@
+package composer
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+//go:generate go run gen_schema.go
+
+// the package name rules of Composer, vendor and name have to be lowercase
+var packageNamePattern = regexp.MustCompile(`^[a-z0-9]([_.-]?[a-z0-9]+)*/[a-z0-9](([_.]|-{1,2})?[a-z0-9]+)*$`)
+
+var composerJsonSchema = mustLoadComposerJsonSchema()
+
+// ValidationError describes a single problem of a composer.json
+type ValidationError struct {
+	// Path to the invalid value, e.g. "require.php" or "(root)"
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (e ValidationError) String() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// ValidateComposerJson validates the given composer.json against the Composer schema and the package name
+// rules, returns all problems found. An error is only returned if the validation itself failed.
+func ValidateComposerJson(composerJson map[string]interface{}) ([]ValidationError, error) {
+	result, err := composerJsonSchema.Validate(gojsonschema.NewGoLoader(composerJson))
+	if err != nil {
+		return nil, errors.Wrap(err, "could not validate composer.json")
+	}
+
+	var validationErrors []ValidationError
+
+	for _, resultError := range result.Errors() {
+		validationErrors = append(validationErrors, ValidationError{
+			Path:    resultError.Field(),
+			Message: resultError.Description(),
+		})
+	}
+
+	// type errors of the name are already reported by the schema
+	if name, ok := composerJson["name"].(string); ok {
+		if nameError := validatePackageName(name); nameError != nil {
+			validationErrors = append(validationErrors, *nameError)
+		}
+	}
+
+	sort.SliceStable(validationErrors, func(i, j int) bool {
+		return validationErrors[i].Path < validationErrors[j].Path
+	})
+
+	return validationErrors, nil
+}
+
+// mustLoadComposerJsonSchema compiles the official Composer schema the way Composer validates packages it installs:
+// all properties are optional, unknown properties are allowed and formats are not checked
+func mustLoadComposerJsonSchema() *gojsonschema.Schema {
+	var schema map[string]interface{}
+	if err := json.Unmarshal([]byte(composerJsonSchemaSource), &schema); err != nil {
+		panic(errors.Wrapf(err, "could not parse the composer schema of %s", composerJsonSchemaVersion))
+	}
+
+	// the lax mode of Composer (JsonFile::LAX_SCHEMA)
+	schema["additionalProperties"] = true
+	delete(schema, "required")
+
+	removeFormats(schema)
+
+	compiled, err := gojsonschema.NewSchema(gojsonschema.NewGoLoader(schema))
+	if err != nil {
+		panic(errors.Wrapf(err, "could not compile the composer schema of %s", composerJsonSchemaVersion))
+	}
+
+	return compiled
+}
+
+// removeFormats removes all format constraints (e.g. "uri" or "email") of the schema, Composer ignores them
+func removeFormats(schema interface{}) {
+	switch value := schema.(type) {
+	case map[string]interface{}:
+		if _, ok := value["format"].(string); ok {
+			delete(value, "format")
+		}
+
+		for _, child := range value {
+			removeFormats(child)
+		}
+	case []interface{}:
+		for _, child := range value {
+			removeFormats(child)
+		}
+	}
+}
+
+// validatePackageName checks if the given name follows the Composer rules, i.e. lowercase "vendor/name"
+func validatePackageName(name string) *ValidationError {
+	if packageNamePattern.MatchString(name) {
+		return nil
+	}
+
+	message := "must be in the form of \"vendor/name\" and only contain a-z, 0-9, \".\", \"_\" and \"-\""
+	if strings.ToLower(name) != name {
+		message = "vendor and name must be lowercase"
+	}
+
+	return &ValidationError{Path: "name", Message: message}
+}
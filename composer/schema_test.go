@@ -0,0 +1,99 @@
+package composer
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func validateTestComposerJson(t *testing.T, data string) []ValidationError {
+	var composerJson map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(data), &composerJson))
+
+	validationErrors, err := ValidateComposerJson(composerJson)
+	assert.Nil(t, err)
+	return validationErrors
+}
+
+func TestValidateComposerJson(t *testing.T) {
+	validationErrors := validateTestComposerJson(t, `{
+		"name": "atomicptr/test-package",
+		"type": "library",
+		"license": ["MIT"],
+		"authors": [{"name": "Test", "email": "test@example.com"}],
+		"require": {"php": "^7.2"},
+		"autoload": {"psr-4": {"Atomicptr\\Test\\": "src/"}},
+		"extra": {"branch-alias": {"dev-master": "1.0.x-dev"}},
+		"custom-property": true
+	}`)
+
+	assert.Empty(t, validationErrors)
+}
+
+func TestValidateComposerJsonInvalidTypes(t *testing.T) {
+	validationErrors := validateTestComposerJson(t, `{
+		"name": 42,
+		"type": ["library"],
+		"require": {"php": 7}
+	}`)
+
+	assert.Len(t, validationErrors, 3)
+	assert.EqualValues(t, "name", validationErrors[0].Path)
+	assert.EqualValues(t, "require.php", validationErrors[1].Path)
+	assert.EqualValues(t, "type", validationErrors[2].Path)
+}
+
+func TestValidateComposerJsonInvalidEnum(t *testing.T) {
+	validationErrors := validateTestComposerJson(t, `{
+		"name": "atomicptr/test",
+		"minimum-stability": "unstable"
+	}`)
+
+	assert.Len(t, validationErrors, 1)
+	assert.EqualValues(t, "minimum-stability", validationErrors[0].Path)
+}
+
+func TestValidateComposerJsonIgnoresFormats(t *testing.T) {
+	validationErrors := validateTestComposerJson(t, `{
+		"name": "atomicptr/test",
+		"homepage": "example.com",
+		"authors": [{"name": "Test", "email": "test at example.com", "homepage": "www.example.com"}],
+		"support": {"email": "support", "issues": "issues/", "irc": "#test"}
+	}`)
+
+	assert.Empty(t, validationErrors)
+}
+
+func TestValidateComposerJsonWithoutRequiredProperties(t *testing.T) {
+	validationErrors := validateTestComposerJson(t, `{
+		"name": "atomicptr/test"
+	}`)
+
+	assert.Empty(t, validationErrors)
+}
+
+func TestValidateComposerJsonNestedAdditionalProperties(t *testing.T) {
+	validationErrors := validateTestComposerJson(t, `{
+		"name": "atomicptr/test",
+		"authors": [{"name": "Test", "twitter": "@test"}]
+	}`)
+
+	assert.Len(t, validationErrors, 1)
+	assert.EqualValues(t, "authors.0", validationErrors[0].Path)
+}
+
+func TestValidatePackageName(t *testing.T) {
+	assert.Nil(t, validatePackageName("atomicptr/test"))
+	assert.Nil(t, validatePackageName("my-vendor/my.package_name"))
+	assert.Nil(t, validatePackageName("vendor/my--package"))
+
+	nameError := validatePackageName("Atomicptr/Test")
+	assert.NotNil(t, nameError)
+	assert.EqualValues(t, "name", nameError.Path)
+	assert.Contains(t, nameError.Message, "lowercase")
+
+	assert.NotNil(t, validatePackageName("test"))
+	assert.NotNil(t, validatePackageName("atomicptr/test/package"))
+	assert.NotNil(t, validatePackageName("atomicptr/-test"))
+}
@@ -1,9 +1,18 @@
 package composer
 
+import "time"
+
 type VersionInfo struct {
 	Name    string     `json:"name"`
 	Source  SourceInfo `json:"source"`
 	Type    string     `json:"type"`
 	Version string     `json:"version"`
 	Uid     int64      `json:"uid"`
+	// Time is the release date of the version
+	Time     *time.Time `json:"time,omitempty"`
+	Homepage string     `json:"homepage,omitempty"`
+	// Support contains links for support like the source or the issue tracker
+	Support map[string]string `json:"support,omitempty"`
+	// Abandoned is true or the name of the package which replaces this one, composer warns about abandoned packages
+	Abandoned interface{} `json:"abandoned,omitempty"`
 }
@@ -1,6 +1,7 @@
 package gitlab
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
@@ -8,6 +9,8 @@ import (
 
 	"github.com/pkg/errors"
 	"github.com/xanzy/go-gitlab"
+
+	"github.com/atomicptr/gitlab-composer-integration/composer"
 )
 
 type ComposerProject struct {
@@ -19,6 +22,19 @@ type ComposerProject struct {
 	ComposerJson map[string]interface{}
 }
 
+// invalidProjectError marks projects which can't be used as composer package, unlike API errors they are
+// not caused by Gitlab being unavailable
+type invalidProjectError struct {
+	error
+	status      ScanStatus
+	packageName string
+	diagnostics []composer.ValidationError
+}
+
+func newInvalidProjectError(status ScanStatus, packageName string, err error) error {
+	return &invalidProjectError{error: err, status: status, packageName: packageName}
+}
+
 func (project *ComposerProject) GitUrl() string {
 	url := project.Project.SSHURLToRepo
 
@@ -30,31 +46,145 @@ func (project *ComposerProject) GitUrl() string {
 }
 
 func (project *ComposerProject) Type() string {
-	if composerType, ok := project.ComposerJson["type"]; ok {
-		return composerType.(string)
+	if composerType, ok := project.ComposerJson["type"].(string); ok && composerType != "" {
+		return composerType
 	}
 
 	return "library" // because this is the default
 }
 
-func (c *Client) createComposerProject(project *gitlab.Project, file *gitlab.File) (*ComposerProject, error) {
+// Description returns the description of the package
+func (project *ComposerProject) Description() string {
+	description, _ := project.ComposerJson["description"].(string)
+	return description
+}
+
+// License returns the licenses of the package, composer allows a single license or a list of them
+func (project *ComposerProject) License() []string {
+	switch license := project.ComposerJson["license"].(type) {
+	case string:
+		return []string{license}
+	case []interface{}:
+		return toStringSlice(license)
+	}
+
+	return nil
+}
+
+// Abandoned checks if the package is abandoned, either because the composer.json says so or because the project is
+// archived. The second return value is the name of the package replacing this one, it might be empty.
+func (project *ComposerProject) Abandoned() (bool, string) {
+	switch abandoned := project.ComposerJson["abandoned"].(type) {
+	case string:
+		if abandoned != "" {
+			return true, abandoned
+		}
+	case bool:
+		if abandoned {
+			return true, ""
+		}
+	}
+
+	return project.Project != nil && project.Project.Archived, ""
+}
+
+// Keywords returns the keywords of the package
+func (project *ComposerProject) Keywords() []string {
+	keywords, _ := project.ComposerJson["keywords"].([]interface{})
+	return toStringSlice(keywords)
+}
+
+// Require returns the packages required by the package
+func (project *ComposerProject) Require() map[string]string {
+	return project.links("require")
+}
+
+// RequireDev returns the packages required by the package for development
+func (project *ComposerProject) RequireDev() map[string]string {
+	return project.links("require-dev")
+}
+
+// links returns package links like require with their version constraints
+func (project *ComposerProject) links(key string) map[string]string {
+	values, ok := project.ComposerJson[key].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	links := make(map[string]string)
+	for name, value := range values {
+		if constraint, ok := value.(string); ok {
+			links[name] = constraint
+		}
+	}
+
+	return links
+}
+
+func toStringSlice(values []interface{}) []string {
+	var result []string
+	for _, value := range values {
+		if str, ok := value.(string); ok {
+			result = append(result, str)
+		}
+	}
+	return result
+}
+
+func (c *Client) createComposerProject(
+	ctx context.Context,
+	project *gitlab.Project,
+	file *gitlab.File,
+) (*ComposerProject, error) {
 	// determine composer project name and json file
 	data, err := base64.StdEncoding.DecodeString(file.Content)
 	if err != nil {
-		return nil, err
+		return nil, newInvalidProjectError(ScanStatusInvalidJson, "", err)
 	}
 
 	var composerJson map[string]interface{}
 	err = json.Unmarshal(data, &composerJson)
 	if err != nil {
-		return nil, errors.Wrapf(err, "could not parse composer.json in project %s", project.PathWithNamespace)
+		return nil, newInvalidProjectError(
+			ScanStatusInvalidJson,
+			"",
+			errors.Wrapf(err, "could not parse composer.json in project %s", project.PathWithNamespace),
+		)
 	}
 
 	if _, ok := composerJson["name"]; !ok {
-		return nil, fmt.Errorf("composer.json has no name in project %s", project.PathWithNamespace)
+		return nil, newInvalidProjectError(
+			ScanStatusMissingName,
+			"",
+			fmt.Errorf("composer.json has no name in project %s", project.PathWithNamespace),
+		)
+	}
+
+	diagnostics, err := composer.ValidateComposerJson(composerJson)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not validate composer.json in project %s", project.PathWithNamespace)
+	}
+
+	name, _ := composerJson["name"].(string)
+
+	if len(diagnostics) > 0 {
+		messages := make([]string, len(diagnostics))
+		for i, diagnostic := range diagnostics {
+			messages[i] = diagnostic.String()
+		}
+
+		return nil, &invalidProjectError{
+			error: fmt.Errorf(
+				"composer.json in project %s is invalid: %s",
+				project.PathWithNamespace,
+				strings.Join(messages, "; "),
+			),
+			status:      ScanStatusInvalidSchema,
+			packageName: name,
+			diagnostics: diagnostics,
+		}
 	}
 
-	name := composerJson["name"].(string)
 	vendor := extractVendorFromComposerName(name)
 
 	// determine head commit
@@ -63,19 +193,23 @@ func (c *Client) createComposerProject(project *gitlab.Project, file *gitlab.Fil
 			Page:    0,
 			PerPage: 1,
 		},
-	})
+	}, gitlab.WithContext(ctx))
 	if err != nil {
 		return nil, errors.Wrapf(err, "could not determine HEAD commit in project %s", project.PathWithNamespace)
 	}
 
 	if len(commits) == 0 {
-		return nil, fmt.Errorf("could not find any commits in project %s", project.PathWithNamespace)
+		return nil, newInvalidProjectError(
+			ScanStatusNoCommits,
+			name,
+			fmt.Errorf("could not find any commits in project %s", project.PathWithNamespace),
+		)
 	}
 
 	headCommit := commits[0]
 
 	// determine tags
-	tags, _, err := c.gitlab.Tags.ListTags(project.ID, &gitlab.ListTagsOptions{})
+	tags, _, err := c.gitlab.Tags.ListTags(project.ID, &gitlab.ListTagsOptions{}, gitlab.WithContext(ctx))
 	if err != nil {
 		return nil, errors.Wrapf(err, "could not read tags in project %s", project.PathWithNamespace)
 	}
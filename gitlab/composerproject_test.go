@@ -1,11 +1,13 @@
 package gitlab
 
 import (
+	"context"
 	"encoding/base64"
 	"io/ioutil"
 	"log"
 	"testing"
 
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/xanzy/go-gitlab"
 )
@@ -44,6 +46,16 @@ func TestTypeDefault(t *testing.T) {
 	assert.EqualValues(t, "library", project.Type())
 }
 
+func TestTypeInvalid(t *testing.T) {
+	project := ComposerProject{
+		ComposerJson: map[string]interface{}{
+			"type": 42.0,
+		},
+	}
+
+	assert.EqualValues(t, "library", project.Type())
+}
+
 func TestExtractVendorFromComposerName(t *testing.T) {
 	values := map[string]string{
 		"package-name-without-vendor": "",
@@ -84,6 +96,41 @@ func TestCreateComposerProjectInvalidJsonWithoutName(t *testing.T) {
 	assert.NotNil(t, err)
 }
 
+func TestCreateComposerProjectNameIsNoString(t *testing.T) {
+	_, _, gitlabClient := gitlabTestServerSetup()
+	_, err := tryCreateComposerProjectWithContent(
+		gitlabClient,
+		base64.StdEncoding.EncodeToString(
+			[]byte(`{"name": ["atomicptr/test-package"]}`),
+		),
+	)
+	assert.NotNil(t, err)
+
+	invalidErr, ok := errors.Cause(err).(*invalidProjectError)
+	assert.True(t, ok)
+	assert.EqualValues(t, ScanStatusInvalidSchema, invalidErr.status)
+	assert.Len(t, invalidErr.diagnostics, 1)
+	assert.EqualValues(t, "name", invalidErr.diagnostics[0].Path)
+}
+
+func TestCreateComposerProjectInvalidSchema(t *testing.T) {
+	_, _, gitlabClient := gitlabTestServerSetup()
+	_, err := tryCreateComposerProjectWithContent(
+		gitlabClient,
+		base64.StdEncoding.EncodeToString(
+			[]byte(`{"name": "Atomicptr/Test-Package", "require": {"php": 7}}`),
+		),
+	)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "require.php")
+
+	invalidErr, ok := errors.Cause(err).(*invalidProjectError)
+	assert.True(t, ok)
+	assert.EqualValues(t, ScanStatusInvalidSchema, invalidErr.status)
+	assert.EqualValues(t, "Atomicptr/Test-Package", invalidErr.packageName)
+	assert.Len(t, invalidErr.diagnostics, 2)
+}
+
 func TestCreateComposerProjectCommitApiError(t *testing.T) {
 	const composerJson = `{
 		"name": "atomicptr/test-package"
@@ -158,9 +205,72 @@ func tryCreateComposerProjectWithContent(gitlabClient *gitlab.Client, content st
 	}
 
 	return client.createComposerProject(
+		context.Background(),
 		&gitlab.Project{},
 		&gitlab.File{
 			Content: content,
 		},
 	)
 }
+
+func TestDescriptionKeywordsAndLicense(t *testing.T) {
+	project := ComposerProject{
+		ComposerJson: map[string]interface{}{
+			"description": "A test package",
+			"license":     "MIT",
+			"keywords":    []interface{}{"test", "example"},
+		},
+	}
+
+	assert.EqualValues(t, []string{"test", "example"}, project.Keywords())
+
+	assert.EqualValues(t, "A test package", project.Description())
+	assert.EqualValues(t, []string{"MIT"}, project.License())
+
+	project.ComposerJson["license"] = []interface{}{"MIT", "GPL-3.0-or-later"}
+	assert.EqualValues(t, []string{"MIT", "GPL-3.0-or-later"}, project.License())
+
+	assert.EqualValues(t, "", (&ComposerProject{}).Description())
+	assert.Nil(t, (&ComposerProject{}).Keywords())
+	assert.Nil(t, (&ComposerProject{}).License())
+}
+
+func TestRequire(t *testing.T) {
+	project := ComposerProject{
+		ComposerJson: map[string]interface{}{
+			"require":     map[string]interface{}{"php": ">=7.2", "invalid": 42.0},
+			"require-dev": map[string]interface{}{"phpunit/phpunit": "^8.0"},
+		},
+	}
+
+	assert.EqualValues(t, map[string]string{"php": ">=7.2"}, project.Require())
+	assert.EqualValues(t, map[string]string{"phpunit/phpunit": "^8.0"}, project.RequireDev())
+	assert.Nil(t, (&ComposerProject{}).Require())
+}
+
+func TestAbandoned(t *testing.T) {
+	project := ComposerProject{Project: &gitlab.Project{}}
+
+	abandoned, replacement := project.Abandoned()
+	assert.False(t, abandoned)
+	assert.Empty(t, replacement)
+
+	project.Project.Archived = true
+	abandoned, replacement = project.Abandoned()
+	assert.True(t, abandoned)
+	assert.Empty(t, replacement)
+
+	project.ComposerJson = map[string]interface{}{"abandoned": "atomicptr/replacement"}
+	abandoned, replacement = project.Abandoned()
+	assert.True(t, abandoned)
+	assert.EqualValues(t, "atomicptr/replacement", replacement)
+
+	project.Project.Archived = false
+	project.ComposerJson = map[string]interface{}{"abandoned": true}
+	abandoned, _ = project.Abandoned()
+	assert.True(t, abandoned)
+
+	project.ComposerJson = map[string]interface{}{"abandoned": ""}
+	abandoned, _ = project.Abandoned()
+	assert.False(t, abandoned)
+}
@@ -1,6 +1,7 @@
 package gitlab
 
 import (
+	"context"
 	"crypto/tls"
 	"github.com/pkg/errors"
 	"log"
@@ -9,29 +10,97 @@ import (
 	"time"
 
 	"github.com/xanzy/go-gitlab"
+
+	"github.com/atomicptr/gitlab-composer-integration/composer"
 )
 
 const ApiSuffix = "/api/v4"
 
+// ErrProjectNotFound is returned if a project does not exist (anymore) or is not visible to the token
+var ErrProjectNotFound = errors.New("project not found")
+
 type Client struct {
 	gitlab *gitlab.Client
 	logger *log.Logger
 }
 
-func New(baseUrl, token string, logger *log.Logger) *Client {
+// TransportMiddleware wraps the transport used for all requests to Gitlab, e.g. for instrumentation
+type TransportMiddleware func(next http.RoundTripper) http.RoundTripper
+
+// ScanResult is the result of scanning all Gitlab projects for composer packages
+type ScanResult struct {
+	Projects []*ComposerProject
+	// Outcomes contains the outcome of every scanned project
+	Outcomes []*ProjectOutcome
+}
+
+// ScanStatus describes the outcome of scanning a single project
+type ScanStatus string
+
+const (
+	ScanStatusFound          ScanStatus = "found"
+	ScanStatusNoComposerJson ScanStatus = "skipped-no-composer-json"
+	ScanStatusInvalidJson    ScanStatus = "invalid-json"
+	ScanStatusMissingName    ScanStatus = "missing-name"
+	ScanStatusInvalidSchema  ScanStatus = "invalid-schema"
+	ScanStatusNoCommits      ScanStatus = "no-commits"
+	ScanStatusApiError       ScanStatus = "api-error"
+)
+
+// ProjectOutcome is the result of scanning a single project
+type ProjectOutcome struct {
+	Project *gitlab.Project
+	Status  ScanStatus
+	// Package is the name of the composer package, only known if the composer.json could be read
+	Package string
+	Err     error
+	// Diagnostics lists the problems of an invalid composer.json
+	Diagnostics []composer.ValidationError
+	ScannedAt   time.Time
+}
+
+// Failures returns the outcomes of all projects which have a composer.json but could not be scanned, either
+// because they are not usable or because of errors of the Gitlab API
+func (r *ScanResult) Failures() []*ProjectOutcome {
+	var failures []*ProjectOutcome
+	for _, outcome := range r.Outcomes {
+		if outcome.Err != nil {
+			failures = append(failures, outcome)
+		}
+	}
+	return failures
+}
+
+// New creates a client for the given Gitlab instance, if tlsConfig is nil the default TLS configuration
+// (verifying against the system certificates) is used
+func New(
+	baseUrl, token string,
+	tlsConfig *tls.Config,
+	logger *log.Logger,
+	middlewares ...TransportMiddleware,
+) *Client {
+	var transport http.RoundTripper = &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		TLSClientConfig:       tlsConfig,
+	}
+
+	for _, middleware := range middlewares {
+		transport = middleware(transport)
+	}
+
+	// every attempt passes the middlewares on its own
+	transport = newRetryTransport(transport, logger)
+
 	httpClient := &http.Client{
-		Transport: &http.Transport{
-			Proxy: http.ProxyFromEnvironment,
-			DialContext: (&net.Dialer{
-				Timeout:   30 * time.Second,
-				KeepAlive: 30 * time.Second,
-			}).DialContext,
-			ForceAttemptHTTP2:     true,
-			IdleConnTimeout:       90 * time.Second,
-			TLSHandshakeTimeout:   10 * time.Second,
-			ExpectContinueTimeout: 1 * time.Second,
-			TLSClientConfig:       &tls.Config{InsecureSkipVerify: true},
-		},
+		Transport: transport,
 	}
 
 	git := gitlab.NewClient(httpClient, token)
@@ -52,21 +121,25 @@ func New(baseUrl, token string, logger *log.Logger) *Client {
 	return client
 }
 
-func (c *Client) Validate() error {
-	_, _, err := c.gitlab.Projects.ListProjects(&gitlab.ListProjectsOptions{})
+func (c *Client) Validate(ctx context.Context) error {
+	_, _, err := c.gitlab.Projects.ListProjects(&gitlab.ListProjectsOptions{}, gitlab.WithContext(ctx))
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (c *Client) FindAllComposerProjects() ([]*ComposerProject, error) {
+// ProgressFunc is called with the amount of projects scanned so far
+type ProgressFunc func(scanned int)
+
+// FindAllComposerProjects scans all projects for composer packages, the scan stops once the context is done.
+// The progress function is called after every page of projects, it may be nil.
+func (c *Client) FindAllComposerProjects(ctx context.Context, progress ProgressFunc) (*ScanResult, error) {
 	running := true
 
-	const ComposerFileName = "composer.json"
 	const PageSize = 50
 
-	var composerProjects []*ComposerProject
+	result := &ScanResult{}
 
 	page := 0
 	for running {
@@ -75,32 +148,127 @@ func (c *Client) FindAllComposerProjects() ([]*ComposerProject, error) {
 				Page:    page,
 				PerPage: PageSize,
 			},
-		})
+		}, gitlab.WithContext(ctx))
 		if err != nil {
-			return nil, err
+			return nil, errors.Wrap(err, "could not list projects")
 		}
 
 		for _, project := range projects {
-			file, _, err := c.gitlab.RepositoryFiles.GetFile(project.ID, ComposerFileName, &gitlab.GetFileOptions{
-				Ref: gitlab.String(project.DefaultBranch),
-			})
-
-			if err == nil && file != nil {
-				composerProject, err := c.createComposerProject(project, file)
-				if err != nil {
-					c.logger.Println(errors.Wrap(err, "error: invalid composer project"))
-					continue
-				}
-				composerProjects = append(composerProjects, composerProject)
+			composerProject, outcome, err := c.scanProject(ctx, project)
+			if err != nil {
+				return nil, err
+			}
+
+			result.Outcomes = append(result.Outcomes, outcome)
+
+			if composerProject != nil {
+				result.Projects = append(result.Projects, composerProject)
 			}
 		}
 
+		if progress != nil {
+			progress(len(result.Outcomes))
+		}
+
 		page++
 		if len(projects) < PageSize {
 			running = false
 		}
 	}
 
-	c.logger.Printf("%d projects found", len(composerProjects))
-	return composerProjects, nil
+	c.logger.Printf("%d projects found", len(result.Projects))
+	return result, nil
+}
+
+// ScanProject scans the project with the given id
+func (c *Client) ScanProject(ctx context.Context, projectId int) (*ComposerProject, *ProjectOutcome, error) {
+	project, _, err := c.gitlab.Projects.GetProject(projectId, &gitlab.GetProjectOptions{}, gitlab.WithContext(ctx))
+	if isNotFound(err) {
+		return nil, nil, errors.Wrapf(ErrProjectNotFound, "could not read project %d", projectId)
+	}
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "could not read project %d", projectId)
+	}
+
+	return c.scanProject(ctx, project)
+}
+
+// GetRawFile returns the content of the file at the given path and ref (a branch, tag or commit) of the
+// project, the second return value is false if the file does not exist
+func (c *Client) GetRawFile(ctx context.Context, projectId int, path, ref string) ([]byte, bool, error) {
+	data, _, err := c.gitlab.RepositoryFiles.GetRawFile(projectId, path, &gitlab.GetRawFileOptions{
+		Ref: gitlab.String(ref),
+	}, gitlab.WithContext(ctx))
+
+	if isNotFound(err) {
+		return nil, false, nil
+	}
+
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "could not read %s of project %d", path, projectId)
+	}
+
+	return data, true, nil
+}
+
+// scanProject determines the outcome of scanning the given project, an error is only returned if the context
+// is done. The composer project is nil unless the project was found to be a valid composer package.
+func (c *Client) scanProject(ctx context.Context, project *gitlab.Project) (*ComposerProject, *ProjectOutcome, error) {
+	const ComposerFileName = "composer.json"
+
+	file, _, err := c.gitlab.RepositoryFiles.GetFile(project.ID, ComposerFileName, &gitlab.GetFileOptions{
+		Ref: gitlab.String(project.DefaultBranch),
+	}, gitlab.WithContext(ctx))
+
+	if ctx.Err() != nil {
+		return nil, nil, ctx.Err()
+	}
+
+	outcome := &ProjectOutcome{Project: project, ScannedAt: time.Now()}
+
+	// projects without composer.json are no composer packages
+	if isNotFound(err) || (err == nil && file == nil) {
+		outcome.Status = ScanStatusNoComposerJson
+		return nil, outcome, nil
+	}
+
+	if err != nil {
+		outcome.Status = ScanStatusApiError
+		outcome.Err = errors.Wrapf(err, "could not read composer.json in project %s", project.PathWithNamespace)
+		c.logger.Println(outcome.Err)
+		return nil, outcome, nil
+	}
+
+	composerProject, err := c.createComposerProject(ctx, project, file)
+	if ctx.Err() != nil {
+		return nil, nil, ctx.Err()
+	}
+
+	if err != nil {
+		outcome.Err = err
+
+		if invalidErr, ok := errors.Cause(err).(*invalidProjectError); ok {
+			c.logger.Println(errors.Wrap(err, "error: invalid composer project"))
+			outcome.Status = invalidErr.status
+			outcome.Package = invalidErr.packageName
+			outcome.Diagnostics = invalidErr.diagnostics
+		} else {
+			c.logger.Println(err)
+			outcome.Status = ScanStatusApiError
+		}
+
+		return nil, outcome, nil
+	}
+
+	outcome.Status = ScanStatusFound
+	outcome.Package = composerProject.Name
+	return composerProject, outcome, nil
+}
+
+// isNotFound returns true if Gitlab responded with 404
+func isNotFound(err error) bool {
+	if errorResponse, ok := errors.Cause(err).(*gitlab.ErrorResponse); ok {
+		return errorResponse.Response != nil && errorResponse.Response.StatusCode == http.StatusNotFound
+	}
+	return false
 }
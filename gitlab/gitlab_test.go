@@ -1,24 +1,48 @@
 package gitlab
 
 import (
+	"context"
 	"encoding/base64"
 	"fmt"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"io/ioutil"
 	"log"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 )
 
 func TestNewWithInvalidBaseUrl(t *testing.T) {
-	client := New("https://invalid url", "this is ma token", log.New(ioutil.Discard, "", 0))
+	client := New("https://invalid url", "this is ma token", nil, log.New(ioutil.Discard, "", 0))
 	assert.Nil(t, client)
 }
 
 func TestNew(t *testing.T) {
-	client := New("https://gitlab.com", "this is ma token", log.New(ioutil.Discard, "", 0))
+	client := New("https://gitlab.com", "this is ma token", nil, log.New(ioutil.Discard, "", 0))
 	assert.EqualValues(t, "https://gitlab.com/api/v4/", client.gitlab.BaseURL().String())
 }
 
+func TestNewWithMiddleware(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		_, _ = fmt.Fprint(writer, "[]")
+	}))
+	defer server.Close()
+
+	calls := 0
+	middleware := func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(request *http.Request) (*http.Response, error) {
+			calls++
+			return next.RoundTrip(request)
+		})
+	}
+
+	client := New(server.URL, "this is ma token", nil, log.New(ioutil.Discard, "", 0), middleware)
+
+	assert.Nil(t, client.Validate(context.Background()))
+	assert.EqualValues(t, 1, calls)
+}
+
 func TestValidateApiError(t *testing.T) {
 	_, _, gitlabClient := gitlabTestServerSetup()
 
@@ -27,7 +51,7 @@ func TestValidateApiError(t *testing.T) {
 		logger: log.New(ioutil.Discard, "", 0),
 	}
 
-	assert.NotNil(t, client.Validate())
+	assert.NotNil(t, client.Validate(context.Background()))
 }
 
 func TestValidate(t *testing.T) {
@@ -40,7 +64,7 @@ func TestValidate(t *testing.T) {
 		logger: log.New(ioutil.Discard, "", 0),
 	}
 
-	assert.Nil(t, client.Validate())
+	assert.Nil(t, client.Validate(context.Background()))
 }
 
 func TestFindAllComposerProjectsListApiError(t *testing.T) {
@@ -51,7 +75,7 @@ func TestFindAllComposerProjectsListApiError(t *testing.T) {
 		logger: log.New(ioutil.Discard, "", 0),
 	}
 
-	_, err := client.FindAllComposerProjects()
+	_, err := client.FindAllComposerProjects(context.Background(), nil)
 
 	assert.NotNil(t, err)
 }
@@ -93,9 +117,12 @@ func TestFindAllComposerProjectsInvalidJson(t *testing.T) {
 		logger: log.New(ioutil.Discard, "", 0),
 	}
 
-	_, err := client.FindAllComposerProjects()
+	result, err := client.FindAllComposerProjects(context.Background(), nil)
 
 	assert.Nil(t, err)
+	assert.Empty(t, result.Projects)
+	assert.Len(t, result.Failures(), 1)
+	assert.EqualValues(t, ScanStatusMissingName, result.Outcomes[0].Status)
 }
 
 func TestFindAllComposerProjects(t *testing.T) {
@@ -135,7 +162,147 @@ func TestFindAllComposerProjects(t *testing.T) {
 		logger: log.New(ioutil.Discard, "", 0),
 	}
 
-	_, err := client.FindAllComposerProjects()
+	result, err := client.FindAllComposerProjects(context.Background(), nil)
+
+	assert.Nil(t, err)
+	assert.Len(t, result.Projects, 1)
+	assert.Empty(t, result.Failures())
+	assert.EqualValues(t, ScanStatusFound, result.Outcomes[0].Status)
+	assert.EqualValues(t, "atomicptr/test-package", result.Outcomes[0].Package)
+}
+
+func TestFindAllComposerProjectsFileApiError(t *testing.T) {
+	mux, _, gitlabClient := gitlabTestServerSetup()
+
+	registerApiResult(mux, "projects", `[{"id": 0}]`)
+	mux.HandleFunc(
+		ApiSuffix+"/projects/0/repository/files/composer.json",
+		func(writer http.ResponseWriter, _ *http.Request) {
+			writer.WriteHeader(http.StatusInternalServerError)
+		},
+	)
+
+	client := Client{
+		gitlab: gitlabClient,
+		logger: log.New(ioutil.Discard, "", 0),
+	}
+
+	result, err := client.FindAllComposerProjects(context.Background(), nil)
+
+	assert.Nil(t, err)
+	assert.Empty(t, result.Projects)
+	assert.Len(t, result.Failures(), 1)
+	assert.EqualValues(t, 0, result.Failures()[0].Project.ID)
+	assert.EqualValues(t, ScanStatusApiError, result.Outcomes[0].Status)
+}
+
+func TestFindAllComposerProjectsWithoutComposerJson(t *testing.T) {
+	mux, _, gitlabClient := gitlabTestServerSetup()
+
+	registerApiResult(mux, "projects", `[{"id": 0}]`)
+
+	client := Client{
+		gitlab: gitlabClient,
+		logger: log.New(ioutil.Discard, "", 0),
+	}
+
+	result, err := client.FindAllComposerProjects(context.Background(), nil)
+
+	assert.Nil(t, err)
+	assert.Empty(t, result.Projects)
+	assert.Len(t, result.Outcomes, 1)
+	assert.EqualValues(t, ScanStatusNoComposerJson, result.Outcomes[0].Status)
+}
+
+func TestFindAllComposerProjectsProgress(t *testing.T) {
+	mux, _, gitlabClient := gitlabTestServerSetup()
+
+	registerApiResult(mux, "projects", `[{"id": 0}, {"id": 1}]`)
+
+	client := Client{
+		gitlab: gitlabClient,
+		logger: log.New(ioutil.Discard, "", 0),
+	}
+
+	var progress []int
+	_, err := client.FindAllComposerProjects(context.Background(), func(scanned int) {
+		progress = append(progress, scanned)
+	})
+
+	assert.Nil(t, err)
+	assert.EqualValues(t, []int{2}, progress)
+}
+
+func TestScanProject(t *testing.T) {
+	mux, _, gitlabClient := gitlabTestServerSetup()
+
+	fileData := fmt.Sprintf(
+		`{"file_name": "composer.json", "encoding": "base64", "content": "%s"}`,
+		base64.StdEncoding.EncodeToString([]byte(`{"name": "atomicptr/test-package"}`)),
+	)
+
+	registerApiResult(mux, "projects/42", `{"id": 42, "path_with_namespace": "atomicptr/test"}`)
+	registerApiResult(mux, "projects/42/repository/commits", `[{"id": "1234"}]`)
+	registerApiResult(mux, "projects/42/repository/tags", `[]`)
+	registerApiResult(mux, "projects/42/repository/files/composer.json", fileData)
+
+	client := Client{
+		gitlab: gitlabClient,
+		logger: log.New(ioutil.Discard, "", 0),
+	}
+
+	project, outcome, err := client.ScanProject(context.Background(), 42)
+
+	assert.Nil(t, err)
+	assert.EqualValues(t, "atomicptr/test-package", project.Name)
+	assert.EqualValues(t, ScanStatusFound, outcome.Status)
+	assert.EqualValues(t, 42, outcome.Project.ID)
+}
+
+func TestScanProjectNotFound(t *testing.T) {
+	_, _, gitlabClient := gitlabTestServerSetup()
+
+	client := Client{
+		gitlab: gitlabClient,
+		logger: log.New(ioutil.Discard, "", 0),
+	}
+
+	_, _, err := client.ScanProject(context.Background(), 42)
+
+	assert.EqualValues(t, ErrProjectNotFound, errors.Cause(err))
+}
+
+func TestGetRawFile(t *testing.T) {
+	mux, _, gitlabClient := gitlabTestServerSetup()
+
+	registerApiResult(mux, "projects/42/repository/files/README.md/raw", "# Test")
+	mux.HandleFunc(
+		ApiSuffix+"/projects/43/repository/files/README.md/raw",
+		func(writer http.ResponseWriter, _ *http.Request) {
+			writer.WriteHeader(http.StatusInternalServerError)
+		},
+	)
+
+	client := Client{
+		gitlab: gitlabClient,
+		logger: log.New(ioutil.Discard, "", 0),
+	}
+
+	data, found, err := client.GetRawFile(context.Background(), 42, "README.md", "master")
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.EqualValues(t, "# Test", string(data))
 
+	_, found, err = client.GetRawFile(context.Background(), 42, "CHANGELOG.md", "master")
 	assert.Nil(t, err)
+	assert.False(t, found)
+
+	_, _, err = client.GetRawFile(context.Background(), 43, "README.md", "master")
+	assert.NotNil(t, err)
+}
+
+type roundTripperFunc func(request *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(request *http.Request) (*http.Response, error) {
+	return f(request)
 }
@@ -0,0 +1,200 @@
+package gitlab
+
+import (
+	"context"
+	"crypto/x509"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// the maximum amount of retries of a single request
+	retryMaxAttempts = 5
+	// the delay before the first retry, it doubles with every attempt
+	retryBaseDelay = 500 * time.Millisecond
+	// the upper limit of a single delay, also applies to Retry-After and rate limit resets
+	retryMaxDelay = time.Minute
+)
+
+// retryTransport retries idempotent requests which failed because of network errors, rate limiting or
+// unavailable servers with exponential backoff. It also pauses all requests once Gitlab reports that the
+// rate limit is exhausted.
+type retryTransport struct {
+	next        http.RoundTripper
+	logger      *log.Logger
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+	mutex       sync.Mutex
+	pausedUntil time.Time
+}
+
+func newRetryTransport(next http.RoundTripper, logger *log.Logger) *retryTransport {
+	return &retryTransport{
+		next:        next,
+		logger:      logger,
+		maxAttempts: retryMaxAttempts,
+		baseDelay:   retryBaseDelay,
+		maxDelay:    retryMaxDelay,
+	}
+}
+
+func (t *retryTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	ctx := request.Context()
+
+	for attempt := 0; ; attempt++ {
+		if err := sleep(ctx, t.rateLimitDelay()); err != nil {
+			return nil, err
+		}
+
+		response, err := t.next.RoundTrip(request)
+		if response != nil {
+			t.updateRateLimit(response)
+		}
+
+		if !isIdempotent(request) || attempt >= t.maxAttempts || ctx.Err() != nil || !isRetryable(response, err) {
+			return response, err
+		}
+
+		delay := t.backoff(attempt, response)
+
+		if err != nil {
+			t.logger.Printf("request to %s failed (%s), retrying in %s", request.URL.Path, err, delay)
+		} else {
+			t.logger.Printf("request to %s failed (%s), retrying in %s", request.URL.Path, response.Status, delay)
+
+			// the connection can only be reused if the body was read completely
+			_ = response.Body.Close()
+		}
+
+		if err := sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// backoff returns the delay before the next attempt, Retry-After is honored if it asks for a longer delay
+func (t *retryTransport) backoff(attempt int, response *http.Response) time.Duration {
+	delay := t.baseDelay << uint(attempt)
+	if delay > t.maxDelay || delay <= 0 {
+		delay = t.maxDelay
+	}
+
+	// jitter prevents multiple instances from retrying at exactly the same time
+	delay = delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+
+	if response != nil {
+		if retryAfter, ok := parseRetryAfter(response.Header.Get("Retry-After")); ok && retryAfter > delay {
+			delay = retryAfter
+		}
+	}
+
+	if delay > t.maxDelay {
+		delay = t.maxDelay
+	}
+
+	return delay
+}
+
+// updateRateLimit pauses all further requests until the rate limit resets if it is exhausted
+func (t *retryTransport) updateRateLimit(response *http.Response) {
+	remaining, err := strconv.Atoi(response.Header.Get("RateLimit-Remaining"))
+	if err != nil || remaining > 0 {
+		return
+	}
+
+	reset, err := strconv.ParseInt(response.Header.Get("RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return
+	}
+
+	pausedUntil := time.Unix(reset, 0)
+	if maxPause := time.Now().Add(t.maxDelay); pausedUntil.After(maxPause) {
+		pausedUntil = maxPause
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if pausedUntil.After(t.pausedUntil) {
+		t.logger.Printf("gitlab rate limit exhausted, pausing requests until %s", pausedUntil.Format(time.RFC3339))
+		t.pausedUntil = pausedUntil
+	}
+}
+
+func (t *retryTransport) rateLimitDelay() time.Duration {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return time.Until(t.pausedUntil)
+}
+
+func isIdempotent(request *http.Request) bool {
+	return request.Method == http.MethodGet || request.Method == http.MethodHead
+}
+
+// isRetryable returns true if the request failed for a reason which is likely temporary
+func isRetryable(response *http.Response, err error) bool {
+	if err != nil {
+		return !isCertificateError(err)
+	}
+
+	switch response.StatusCode {
+	case http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+
+	return false
+}
+
+// isCertificateError returns true if the certificate of Gitlab was rejected, retrying won't help here
+func isCertificateError(err error) bool {
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostname x509.HostnameError
+	var invalid x509.CertificateInvalidError
+
+	return errors.As(err, &unknownAuthority) || errors.As(err, &hostname) || errors.As(err, &invalid)
+}
+
+// parseRetryAfter parses the Retry-After header which is either a delay in seconds or a date
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second, seconds >= 0
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return time.Until(date), true
+	}
+
+	return 0, false
+}
+
+// sleep waits for the given duration or until the context is done
+func sleep(ctx context.Context, duration time.Duration) error {
+	if duration <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,163 @@
+package gitlab
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func createTestRetryTransport() *retryTransport {
+	transport := newRetryTransport(http.DefaultTransport, log.New(ioutil.Discard, "", 0))
+	transport.baseDelay = time.Millisecond
+	transport.maxDelay = 10 * time.Millisecond
+	return transport
+}
+
+func createFailingTestServer(failures int, status int, header http.Header) (*httptest.Server, *int) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		calls++
+		if calls <= failures {
+			for key, values := range header {
+				writer.Header()[key] = values
+			}
+			writer.WriteHeader(status)
+			return
+		}
+		writer.WriteHeader(http.StatusOK)
+	}))
+	return server, &calls
+}
+
+func TestRetryTransportRetriesUnavailableServer(t *testing.T) {
+	server, calls := createFailingTestServer(2, http.StatusServiceUnavailable, nil)
+	defer server.Close()
+
+	client := &http.Client{Transport: createTestRetryTransport()}
+
+	response, err := client.Get(server.URL)
+	assert.Nil(t, err)
+	assert.EqualValues(t, http.StatusOK, response.StatusCode)
+	assert.EqualValues(t, 3, *calls)
+}
+
+func TestRetryTransportGivesUp(t *testing.T) {
+	server, calls := createFailingTestServer(100, http.StatusBadGateway, nil)
+	defer server.Close()
+
+	transport := createTestRetryTransport()
+	client := &http.Client{Transport: transport}
+
+	response, err := client.Get(server.URL)
+	assert.Nil(t, err)
+	assert.EqualValues(t, http.StatusBadGateway, response.StatusCode)
+	assert.EqualValues(t, transport.maxAttempts+1, *calls)
+}
+
+func TestRetryTransportDoesNotRetryClientErrors(t *testing.T) {
+	server, calls := createFailingTestServer(1, http.StatusNotFound, nil)
+	defer server.Close()
+
+	client := &http.Client{Transport: createTestRetryTransport()}
+
+	response, err := client.Get(server.URL)
+	assert.Nil(t, err)
+	assert.EqualValues(t, http.StatusNotFound, response.StatusCode)
+	assert.EqualValues(t, 1, *calls)
+}
+
+func TestRetryTransportDoesNotRetryPost(t *testing.T) {
+	server, calls := createFailingTestServer(1, http.StatusServiceUnavailable, nil)
+	defer server.Close()
+
+	client := &http.Client{Transport: createTestRetryTransport()}
+
+	response, err := client.Post(server.URL, "application/json", nil)
+	assert.Nil(t, err)
+	assert.EqualValues(t, http.StatusServiceUnavailable, response.StatusCode)
+	assert.EqualValues(t, 1, *calls)
+}
+
+func TestRetryTransportHonorsRetryAfter(t *testing.T) {
+	header := http.Header{"Retry-After": []string{"1"}}
+	server, calls := createFailingTestServer(1, http.StatusTooManyRequests, header)
+	defer server.Close()
+
+	transport := createTestRetryTransport()
+	transport.maxDelay = time.Second
+	client := &http.Client{Transport: transport}
+
+	start := time.Now()
+	response, err := client.Get(server.URL)
+	assert.Nil(t, err)
+	assert.EqualValues(t, http.StatusOK, response.StatusCode)
+	assert.EqualValues(t, 2, *calls)
+	assert.True(t, time.Since(start) >= time.Second)
+}
+
+func TestRetryTransportStopsWhenContextIsDone(t *testing.T) {
+	server, calls := createFailingTestServer(100, http.StatusServiceUnavailable, nil)
+	defer server.Close()
+
+	transport := createTestRetryTransport()
+	transport.baseDelay = time.Hour
+	transport.maxDelay = time.Hour
+	client := &http.Client{Transport: transport}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	request, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	assert.Nil(t, err)
+
+	_, err = client.Do(request.WithContext(ctx))
+	assert.NotNil(t, err)
+	assert.EqualValues(t, 1, *calls)
+}
+
+func TestRetryTransportPausesWhenRateLimitIsExhausted(t *testing.T) {
+	reset := time.Now().Add(time.Hour).Unix()
+	header := http.Header{
+		"RateLimit-Remaining": []string{"0"},
+		"RateLimit-Reset":     []string{strconv.FormatInt(reset, 10)},
+	}
+	server, _ := createFailingTestServer(1, http.StatusOK, header)
+	defer server.Close()
+
+	transport := createTestRetryTransport()
+	transport.maxDelay = 50 * time.Millisecond
+	client := &http.Client{Transport: transport}
+
+	_, err := client.Get(server.URL)
+	assert.Nil(t, err)
+	assert.True(t, transport.rateLimitDelay() > 0)
+
+	// the pause is limited by the max delay
+	start := time.Now()
+	_, err = client.Get(server.URL)
+	assert.Nil(t, err)
+	assert.True(t, time.Since(start) >= 40*time.Millisecond)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	delay, ok := parseRetryAfter("120")
+	assert.True(t, ok)
+	assert.EqualValues(t, 2*time.Minute, delay)
+
+	delay, ok = parseRetryAfter(time.Now().Add(time.Minute).UTC().Format(http.TimeFormat))
+	assert.True(t, ok)
+	assert.True(t, delay > 50*time.Second && delay <= time.Minute)
+
+	_, ok = parseRetryAfter("")
+	assert.False(t, ok)
+
+	_, ok = parseRetryAfter("soon")
+	assert.False(t, ok)
+}
@@ -0,0 +1,61 @@
+package gitlab
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+)
+
+// TlsOptions configure how the connection to Gitlab is secured
+type TlsOptions struct {
+	// CaFile is a PEM bundle trusted in addition to the system certificates
+	CaFile string
+	// InsecureSkipVerify disables the verification of the Gitlab certificate
+	InsecureSkipVerify bool
+	// ClientCertFile and ClientKeyFile are presented to Gitlab instances which require client certificates
+	ClientCertFile string
+	ClientKeyFile  string
+}
+
+// NewTlsConfig creates the TLS configuration for the connection to Gitlab, certificates are verified
+// against the system certificates unless configured otherwise
+func NewTlsConfig(options TlsOptions) (*tls.Config, error) {
+	config := &tls.Config{
+		InsecureSkipVerify: options.InsecureSkipVerify,
+	}
+
+	if options.CaFile != "" {
+		data, err := ioutil.ReadFile(options.CaFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not read gitlab CA file")
+		}
+
+		rootCas, err := x509.SystemCertPool()
+		if err != nil || rootCas == nil {
+			rootCas = x509.NewCertPool()
+		}
+
+		if !rootCas.AppendCertsFromPEM(data) {
+			return nil, errors.Errorf("gitlab CA file %s contains no certificates", options.CaFile)
+		}
+
+		config.RootCAs = rootCas
+	}
+
+	if (options.ClientCertFile == "") != (options.ClientKeyFile == "") {
+		return nil, errors.New("gitlab client cert file and gitlab client key file have to be set together")
+	}
+
+	if options.ClientCertFile != "" {
+		certificate, err := tls.LoadX509KeyPair(options.ClientCertFile, options.ClientKeyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not load gitlab client certificate")
+		}
+
+		config.Certificates = []tls.Certificate{certificate}
+	}
+
+	return config, nil
+}
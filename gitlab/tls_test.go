@@ -0,0 +1,89 @@
+package gitlab
+
+import (
+	"context"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func createTlsTestServer(t *testing.T) (*httptest.Server, string, func()) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		_, _ = fmt.Fprint(writer, "[]")
+	}))
+
+	dir, err := ioutil.TempDir("", "gci-gitlab-tls")
+	assert.Nil(t, err)
+
+	caFile := path.Join(dir, "ca.crt")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	assert.Nil(t, ioutil.WriteFile(caFile, data, 0600))
+
+	return server, caFile, func() {
+		server.Close()
+		_ = os.RemoveAll(dir)
+	}
+}
+
+func TestNewTlsConfigVerifiesByDefault(t *testing.T) {
+	server, _, cleanup := createTlsTestServer(t)
+	defer cleanup()
+
+	tlsConfig, err := NewTlsConfig(TlsOptions{})
+	assert.Nil(t, err)
+	assert.False(t, tlsConfig.InsecureSkipVerify)
+
+	client := New(server.URL, "this is ma token", tlsConfig, log.New(ioutil.Discard, "", 0))
+	assert.NotNil(t, client.Validate(context.Background()))
+}
+
+func TestNewTlsConfigWithCaFile(t *testing.T) {
+	server, caFile, cleanup := createTlsTestServer(t)
+	defer cleanup()
+
+	tlsConfig, err := NewTlsConfig(TlsOptions{CaFile: caFile})
+	assert.Nil(t, err)
+
+	client := New(server.URL, "this is ma token", tlsConfig, log.New(ioutil.Discard, "", 0))
+	assert.Nil(t, client.Validate(context.Background()))
+}
+
+func TestNewTlsConfigInsecureSkipVerify(t *testing.T) {
+	server, _, cleanup := createTlsTestServer(t)
+	defer cleanup()
+
+	tlsConfig, err := NewTlsConfig(TlsOptions{InsecureSkipVerify: true})
+	assert.Nil(t, err)
+
+	client := New(server.URL, "this is ma token", tlsConfig, log.New(ioutil.Discard, "", 0))
+	assert.Nil(t, client.Validate(context.Background()))
+}
+
+func TestNewTlsConfigWithInvalidCaFile(t *testing.T) {
+	file, err := ioutil.TempFile("", "gci-gitlab-ca")
+	assert.Nil(t, err)
+	defer os.Remove(file.Name())
+
+	_, err = file.WriteString("this is not a certificate")
+	assert.Nil(t, err)
+	assert.Nil(t, file.Close())
+
+	_, err = NewTlsConfig(TlsOptions{CaFile: file.Name()})
+	assert.NotNil(t, err)
+
+	_, err = NewTlsConfig(TlsOptions{CaFile: "/does/not/exist.crt"})
+	assert.NotNil(t, err)
+}
+
+func TestNewTlsConfigWithIncompleteClientCertificate(t *testing.T) {
+	_, err := NewTlsConfig(TlsOptions{ClientCertFile: "/etc/ssl/client.crt"})
+	assert.NotNil(t, err)
+}
@@ -3,13 +3,21 @@ module github.com/atomicptr/gitlab-composer-integration
 go 1.13
 
 require (
+	github.com/alicebob/miniredis/v2 v2.11.4
+	github.com/andybalholm/brotli v1.0.0
 	github.com/ardanlabs/conf v1.2.1
 	github.com/golang/protobuf v1.3.5 // indirect
+	github.com/gomodule/redigo v1.8.1
 	github.com/patrickmn/go-cache v2.1.0+incompatible
 	github.com/pkg/errors v0.9.1
-	github.com/stretchr/testify v1.4.0
+	github.com/prometheus/client_golang v1.5.1
+	github.com/stretchr/testify v1.5.1
 	github.com/xanzy/go-gitlab v0.28.0
+	github.com/xeipuuv/gojsonschema v1.2.0
+	github.com/yuin/goldmark v1.1.30
+	go.etcd.io/bbolt v1.3.4
 	golang.org/x/net v0.0.0-20200301022130-244492dfa37a // indirect
 	golang.org/x/oauth2 v0.0.0-20200107190931-bf48bf16ab8d // indirect
 	google.golang.org/appengine v1.6.5 // indirect
+	gopkg.in/yaml.v2 v2.2.8
 )
@@ -10,17 +10,72 @@ import (
 
 const authRealm = "Composer Repository"
 
+// authenticator checks if a request is authenticated by a single method (e.g. basic auth)
+type authenticator func(request *http.Request) bool
+
 func basicAuth(username, password string, handlerFunc func(http.ResponseWriter, *http.Request)) func(http.ResponseWriter, *http.Request) {
 	if username == "" || password == "" {
 		return handlerFunc
 	}
 
+	return withAuthentication([]authenticator{basicAuthenticator(username, password)}, handlerFunc)
+}
+
+// withAuthentication only calls the handler if any of the authenticators accepts the request, without
+// authenticators every request is allowed
+func withAuthentication(authenticators []authenticator, handlerFunc http.HandlerFunc) http.HandlerFunc {
+	if len(authenticators) == 0 {
+		return handlerFunc
+	}
+
 	return func(writer http.ResponseWriter, request *http.Request) {
-		if !isAuthenticated(username, password, request) {
-			requestAuthentication(writer)
-			return
+		for _, authenticated := range authenticators {
+			if authenticated(request) {
+				handlerFunc(writer, request)
+				return
+			}
 		}
-		handlerFunc(writer, request)
+
+		requestAuthentication(writer)
+	}
+}
+
+func basicAuthenticator(username, password string) authenticator {
+	return func(request *http.Request) bool {
+		return isAuthenticated(username, password, request)
+	}
+}
+
+// clientCertificateAuthenticator accepts requests with a verified TLS client certificate, if identities are
+// given the common name or one of the subject alternative names of the certificate has to match one of them
+func clientCertificateAuthenticator(identities []string) authenticator {
+	return func(request *http.Request) bool {
+		if request.TLS == nil || len(request.TLS.VerifiedChains) == 0 || len(request.TLS.VerifiedChains[0]) == 0 {
+			return false
+		}
+
+		if len(identities) == 0 {
+			return true
+		}
+
+		certificate := request.TLS.VerifiedChains[0][0]
+
+		names := []string{certificate.Subject.CommonName}
+		names = append(names, certificate.DNSNames...)
+		names = append(names, certificate.EmailAddresses...)
+		for _, uri := range certificate.URIs {
+			names = append(names, uri.String())
+		}
+
+		for _, name := range names {
+			for _, identity := range identities {
+				if name != "" && name == identity {
+					return true
+				}
+			}
+		}
+
+		return false
 	}
 }
 
@@ -3,36 +3,243 @@ package service
 import (
 	"os"
 	"path"
+	"strconv"
 	"time"
 
-	"github.com/patrickmn/go-cache"
 	"github.com/pkg/errors"
+
+	"github.com/atomicptr/gitlab-composer-integration/storage"
 )
 
-const indexCacheKey = "index"
+// the duration the leadership of an instance lasts without being extended
+const leadershipTtl = 30 * time.Second
+
+// the interval in which the cache is checked for expiration
+const cacheUpdateInterval = 30 * time.Second
+
+// the time of the last failed full refresh, generations scanned before are stale. It is shared so all instances
+// serving the same generation mark it as stale.
+const refreshFailedCacheKey = "refresh-failed"
 
 func (s *Service) cacheUpdateHandler() {
-	for s.running {
-		_, expirationTime, found := s.cache.GetWithExpiration(indexCacheKey)
+	ticker := time.NewTicker(cacheUpdateInterval)
+	defer ticker.Stop()
+
+	for {
+		s.updateCache()
 
-		// set found to false when expiration time has passed to re-cache data
-		if time.Now().After(expirationTime) {
-			found = false
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.refreshTrigger:
 		}
+	}
+}
 
-		if !found {
-			s.logger.Println("no cache found (or is expired), creating new one")
-			data, err := s.fetchComposerData()
-			if err == nil {
-				s.cache.Set(indexCacheKey, data, cache.DefaultExpiration)
-				s.persistCacheInFile()
-			} else {
-				s.logger.Println(errors.Wrap(err, "could not fetch composer data"))
-			}
+// updateCache creates a new generation if the current one is expired (or a refresh was requested) and this
+// instance is the leader
+func (s *Service) updateCache() {
+	if !s.isLeader() {
+		s.syncWithCurrentGeneration()
+		return
+	}
+
+	request, requested := s.takeRefreshRequest()
+
+	if s.isGenerationExpired() || (requested && request.Full) {
+		s.refreshCache(requested && request.Flush)
+		return
+	}
+
+	if requested && len(request.ProjectIds) > 0 {
+		s.logger.Printf("refreshing projects %v", request.ProjectIds)
+		if err := s.refreshProjects(s.ctx, request.ProjectIds); err != nil {
+			s.logger.Println(err)
 		}
+		return
+	}
+
+	s.syncWithCurrentGeneration()
+}
+
+// syncWithCurrentGeneration picks up the generation which might have been swapped by another instance
+// sharing the same storage (the leader)
+func (s *Service) syncWithCurrentGeneration() {
+	generationId, found := s.currentGenerationId()
+	if !found {
+		return
+	}
+
+	if generationId == s.getSyncedGenerationId() {
+		// refreshing the generation might have failed in the meantime
+		s.syncStale(generationId)
+		return
+	}
+
+	if created, found := s.generationCreated(generationId); found {
+		s.adoptGeneration(generationId, created)
+	}
+}
 
-		// TODO: replace this with a ticker
-		time.Sleep(30 * time.Second)
+// adoptGeneration takes over the state of the current generation which was not created by this instance,
+// e.g. because it was restored from the cache file
+func (s *Service) adoptGeneration(generationId string, created time.Time) {
+	catalogue, _ := s.loadCatalogue(generationId)
+	report, _ := s.loadScanReport()
+
+	s.setSyncedGenerationId(generationId)
+	s.setLastRefresh(created)
+	s.setPublishMetrics(catalogue, len(s.currentDegradedPackages()), report)
+	s.syncStale(generationId)
+	s.markIndexReady()
+}
+
+// syncStale marks the given generation as stale if a full refresh failed after it was scanned
+func (s *Service) syncStale(generationId string) {
+	failed, found := s.getFromCache(refreshFailedCacheKey)
+	if !found {
+		s.setStale(false)
+		return
+	}
+
+	failedAt, err := strconv.ParseInt(string(failed), 10, 64)
+	if err != nil {
+		s.logger.Println(errors.Wrap(err, "could not read the time of the last failed refresh"))
+		return
+	}
+
+	scanned, found := s.generationScanned(generationId)
+	s.setStale(found && time.Unix(failedAt, 0).After(scanned))
+}
+
+// storeRefreshFailure remembers that the full refresh failed at the given time
+func (s *Service) storeRefreshFailure(failedAt time.Time) {
+	err := s.cache.Set(refreshFailedCacheKey, []byte(strconv.FormatInt(failedAt.Unix(), 10)))
+	if err != nil {
+		s.logger.Println(errors.Wrap(err, "could not store refresh failure"))
+	}
+}
+
+// refreshCache scans all projects and swaps in the new generation, if flush is true the generation served
+// before is removed right away
+func (s *Service) refreshCache(flush bool) {
+	s.logger.Println("no cache found (or is expired), creating new one")
+	s.startScanProgress(scanKindFull)
+
+	start := time.Now()
+	previous, hasPrevious := s.currentCatalogue()
+	gen, err := s.fetchComposerData(s.ctx)
+	s.metrics.scanDuration.Observe(time.Since(start).Seconds())
+
+	if s.ctx.Err() != nil {
+		s.logger.Println("scan canceled because the service is shutting down")
+		s.finishScanProgress(s.ctx.Err())
+		return
+	}
+
+	if err == nil {
+		err = s.swapGeneration(gen)
+	}
+
+	s.finishScanProgress(err)
+
+	if err != nil {
+		s.logger.Println(errors.Wrap(err, "could not fetch composer data"))
+
+		// the outcomes of the previous scan are kept, they still describe the packages being served
+		report := &scanReport{Projects: []*projectReport{}}
+		if previousReport, found := s.loadScanReport(); found {
+			report = previousReport
+		}
+
+		report.StartedAt = start
+		report.FinishedAt = time.Now()
+		report.Error = err.Error()
+		s.storeScanReport(report)
+		s.storeRefreshFailure(report.FinishedAt)
+
+		if _, found := s.currentGenerationId(); found {
+			s.logger.Println("serving the previous cache generation (marked as stale) until the next refresh")
+			s.setStale(true)
+		}
+		return
+	}
+
+	if flush {
+		if err := s.dropPreviousGeneration(); err != nil {
+			s.logger.Println(errors.Wrap(err, "could not flush cache"))
+		}
+	}
+
+	s.storeScanReport(gen.report)
+	s.setLastRefresh(gen.created)
+	s.setStale(false)
+	s.markIndexReady()
+	s.persistCacheInFile()
+
+	if hasPrevious {
+		s.reportImpact(previous, gen.sortedCatalogue())
+	}
+}
+
+// leadershipHandler keeps extending the leadership of this instance (or tries to acquire it), this
+// way the leadership does not expire while the leader is busy scanning Gitlab
+func (s *Service) leadershipHandler() {
+	ticker := time.NewTicker(leadershipTtl / 3)
+	defer ticker.Stop()
+
+	for {
+		s.isLeader()
+
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// isLeader returns true if this instance is responsible for refreshing the cache, only storages which are
+// shared between multiple instances need to elect a leader
+func (s *Service) isLeader() bool {
+	elector, ok := s.cache.(storage.Elector)
+	if !ok {
+		s.setLeader(true)
+		return true
+	}
+
+	leader, err := elector.AcquireLeadership(s.instanceId, leadershipTtl)
+	if err != nil {
+		s.logger.Println(errors.Wrap(err, "could not determine leader"))
+		s.setLeader(false)
+		return false
+	}
+
+	s.setLeader(leader)
+	return leader
+}
+
+func (s *Service) releaseLeadership() {
+	elector, ok := s.cache.(storage.Elector)
+	if !ok {
+		return
+	}
+
+	err := elector.ReleaseLeadership(s.instanceId)
+	if err != nil {
+		s.logger.Println(errors.Wrap(err, "could not release leadership"))
+	}
+}
+
+func (s *Service) newCacheStorage() storage.Storage {
+	switch s.config.CacheBackend {
+	case CacheBackendBolt:
+		return storage.NewBolt(s.getCacheDatabasePath())
+	case CacheBackendRedis:
+		return storage.NewRedis(s.config.RedisUrl)
+	default:
+		return storage.NewMemory()
 	}
 }
 
@@ -43,24 +250,57 @@ func (s *Service) restoreFileCacheIfItExists() {
 
 	cachePath := s.getCacheFilePath()
 
-	if _, err := os.Stat(cachePath); os.IsNotExist(err) {
-		s.logger.Printf("can't restore cache from file because \"%s\" does not exist.", cachePath)
-		return
+	// storages which are not kept in memory are persistent by themselves
+	if persister, ok := s.cache.(storage.Persister); ok {
+		if _, err := os.Stat(cachePath); os.IsNotExist(err) {
+			s.logger.Printf("can't restore cache from file because \"%s\" does not exist.", cachePath)
+			return
+		}
+
+		err := persister.LoadFile(cachePath)
+		if errors.Cause(err) == storage.ErrIncompatibleFile {
+			s.logger.Printf("cache file %s can't be used (%s), discarding it and creating a new cache", cachePath, err)
+			if err := os.Remove(cachePath); err != nil {
+				s.logger.Println(errors.Wrap(err, "could not remove incompatible cache file"))
+			}
+			return
+		}
+		if err != nil {
+			s.logger.Printf("could not restore cache from file because %s", err)
+			return
+		}
 	}
 
-	err := s.cache.LoadFile(cachePath)
-	if err != nil {
-		s.logger.Printf("could not restore cache from file because %s", err)
+	generationId, _ := s.currentGenerationId()
+	created, found := s.generationCreated(generationId)
+	if !found {
+		// a shared cache is still used by other instances, e.g. the leader might be writing the first generation
+		if _, shared := s.cache.(storage.Elector); shared {
+			s.logger.Println("the shared cache contains no complete generation yet")
+			return
+		}
+
+		s.logger.Printf("cache %s contains no complete generation, discarding it", cachePath)
+		if err := s.cache.Flush(); err != nil {
+			s.logger.Println(errors.Wrap(err, "could not flush cache"))
+		}
 		return
 	}
 
-	s.logger.Printf("successfully loaded cache from file %s", cachePath)
+	s.adoptGeneration(generationId, created)
+
+	s.logger.Printf("successfully loaded cache from %s", cachePath)
 }
 
 func (s *Service) persistCacheInFile() {
+	persister, ok := s.cache.(storage.Persister)
+	if !ok {
+		return
+	}
+
 	cachePath := s.getCacheFilePath()
 
-	err := s.cache.SaveFile(cachePath)
+	err := persister.SaveFile(cachePath)
 	if err != nil {
 		s.logger.Printf("could not persist cache in file because %s", err)
 		return
@@ -78,3 +318,15 @@ func (s *Service) getCacheFilePath() string {
 
 	return cachePath
 }
+
+// getCacheDatabasePath returns the location of the bolt database, it differs from the cache file of the memory
+// backend so switching the backend does not leave an incompatible file behind
+func (s *Service) getCacheDatabasePath() string {
+	cachePath := s.getCacheFilePath()
+
+	if path.Ext(cachePath) == ".db" {
+		return cachePath
+	}
+
+	return cachePath + ".db"
+}
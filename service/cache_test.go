@@ -0,0 +1,241 @@
+package service
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// createPublishedTestGeneration creates a generation publishing two packages, one of them degraded, and a
+// scan report with a skipped project
+func createPublishedTestGeneration(s *Service) *generation {
+	gen := createTestGeneration(time.Now(), "hash")
+	gen.catalogue["atomicptr/test"] = &catalogueEntry{Name: "atomicptr/test", Versions: []string{"v1.0.0", "v1.1.0"}}
+	gen.catalogue["atomicptr/other"] = &catalogueEntry{Name: "atomicptr/other", Versions: []string{"dev-master"}}
+	gen.degraded = []*degradedPackage{{Name: "atomicptr/other"}}
+	gen.report = &scanReport{Projects: []*projectReport{
+		{ProjectId: 1, Status: scanStatusPublished},
+		{ProjectId: 2, Status: "invalid-json", Degraded: true},
+		{ProjectId: 3, Status: "skipped-no-composer-json"},
+	}}
+
+	s.storeScanReport(gen.report)
+	return gen
+}
+
+func assertPublishMetrics(t *testing.T, s *Service) {
+	assert.EqualValues(t, 2, testutil.ToFloat64(s.metrics.publishedPackages))
+	assert.EqualValues(t, 3, testutil.ToFloat64(s.metrics.publishedVersions))
+	assert.EqualValues(t, 1, testutil.ToFloat64(s.metrics.skippedProjects))
+	assert.EqualValues(t, 1, testutil.ToFloat64(s.metrics.degradedPackages))
+	assert.EqualValues(t, 1, s.getDegradedPackageCount())
+}
+
+func TestIsLeaderWithoutElector(t *testing.T) {
+	s := newTestService("https://gitlab.com")
+
+	assert.True(t, s.isLeader())
+	assert.True(t, s.isLeaderInstance())
+}
+
+func TestIsLeaderSharedStorage(t *testing.T) {
+	server, err := miniredis.Run()
+	assert.Nil(t, err)
+	defer server.Close()
+
+	first := newTestService("https://gitlab.com")
+	second := newTestService("https://gitlab.com")
+
+	for _, s := range []*Service{first, second} {
+		s.config.CacheBackend = CacheBackendRedis
+		s.config.RedisUrl = "redis://" + server.Addr()
+		s.cache = s.newCacheStorage()
+	}
+
+	assert.True(t, first.isLeader())
+	assert.False(t, second.isLeader())
+	assert.False(t, second.isLeaderInstance())
+
+	// the generation created by the leader is served by all instances
+	assert.Nil(t, first.swapGeneration(createTestGeneration(time.Now(), "hash")))
+	generationId, _ := second.currentGenerationId()
+	_, found := second.generationCreated(generationId)
+	assert.True(t, found)
+
+	first.releaseLeadership()
+	assert.True(t, second.isLeader())
+}
+
+func TestOpenCacheBoltSingleInstance(t *testing.T) {
+	first := newTestService("https://gitlab.com")
+	second := newTestService("https://gitlab.com")
+
+	for _, s := range []*Service{first, second} {
+		s.config.CacheBackend = CacheBackendBolt
+		s.cache = s.newCacheStorage()
+	}
+	defer first.cache.Close()
+
+	assert.Nil(t, first.openCache())
+	assert.True(t, first.isLeader())
+
+	// the database is locked by the first instance
+	assert.NotNil(t, second.openCache())
+}
+
+func TestOpenCacheBoltAfterMemory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gci-service")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	for _, cacheFilePath := range []string{path.Join(dir, "cache"), path.Join(dir, "cache.db")} {
+		// the deployment used the memory backend before
+		memory := newTestService("https://gitlab.com")
+		memory.config.CacheFilePath = cacheFilePath
+		assert.Nil(t, memory.cache.Set("key", []byte("value")))
+		memory.persistCacheInFile()
+
+		s := newTestService("https://gitlab.com")
+		s.config.CacheFilePath = cacheFilePath
+		s.config.CacheBackend = CacheBackendBolt
+		s.cache = s.newCacheStorage()
+
+		assert.Nil(t, s.openCache())
+		assert.Nil(t, s.cache.Set("key", []byte("value")))
+		assert.Nil(t, s.cache.Close())
+	}
+}
+
+func TestFlushCacheOnStart(t *testing.T) {
+	s := newTestService("https://gitlab.com")
+	assert.Nil(t, s.cache.Set("key", []byte("value")))
+
+	assert.Nil(t, s.flushCacheOnStart())
+
+	_, found := s.getFromCache("key")
+	assert.False(t, found)
+}
+
+func TestFlushCacheOnStartSharedStorage(t *testing.T) {
+	server, err := miniredis.Run()
+	assert.Nil(t, err)
+	defer server.Close()
+
+	s := newTestService("https://gitlab.com")
+	s.config.CacheBackend = CacheBackendRedis
+	s.config.RedisUrl = "redis://" + server.Addr()
+	s.cache = s.newCacheStorage()
+
+	assert.Nil(t, s.cache.Set("key", []byte("value")))
+
+	// the cache is still served by other instances
+	assert.Nil(t, s.flushCacheOnStart())
+
+	_, found := s.getFromCache("key")
+	assert.True(t, found)
+
+	request, found := s.loadRefreshRequest()
+	assert.True(t, found)
+	assert.True(t, request.Full)
+}
+
+func TestRestoreCacheSetsPublishMetrics(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gci-service")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	first := newTestService("https://gitlab.com")
+	first.config.CacheFilePath = path.Join(dir, "cache")
+	gen := createPublishedTestGeneration(first)
+	assert.Nil(t, first.swapGeneration(gen))
+	first.persistCacheInFile()
+
+	s := newTestService("https://gitlab.com")
+	s.config.CacheFilePath = first.config.CacheFilePath
+	s.restoreFileCacheIfItExists()
+
+	assert.EqualValues(t, gen.created.Unix(), s.getLastRefresh().Unix())
+	assertPublishMetrics(t, s)
+}
+
+func TestSyncWithCurrentGenerationSetsPublishMetrics(t *testing.T) {
+	server, err := miniredis.Run()
+	assert.Nil(t, err)
+	defer server.Close()
+
+	leader := newTestService("https://gitlab.com")
+	follower := newTestService("https://gitlab.com")
+
+	for _, s := range []*Service{leader, follower} {
+		s.config.CacheBackend = CacheBackendRedis
+		s.config.RedisUrl = "redis://" + server.Addr()
+		s.cache = s.newCacheStorage()
+	}
+
+	assert.Nil(t, leader.swapGeneration(createPublishedTestGeneration(leader)))
+
+	follower.syncWithCurrentGeneration()
+	assertPublishMetrics(t, follower)
+}
+
+func TestRestoreCacheKeepsSharedStorage(t *testing.T) {
+	server, err := miniredis.Run()
+	assert.Nil(t, err)
+	defer server.Close()
+
+	leader := newTestService("https://gitlab.com")
+	replica := newTestService("https://gitlab.com")
+
+	for _, s := range []*Service{leader, replica} {
+		s.config.CacheBackend = CacheBackendRedis
+		s.config.RedisUrl = "redis://" + server.Addr()
+		s.cache = s.newCacheStorage()
+	}
+
+	// the leader is still busy with its first scan
+	leader.storeLastKnownGood(1, &lastKnownGood{Name: "atomicptr/test", Vendor: "atomicptr"})
+	_, err = leader.queueRefresh(true)
+	assert.Nil(t, err)
+
+	replica.restoreFileCacheIfItExists()
+
+	_, found := replica.loadLastKnownGood(1)
+	assert.True(t, found)
+	_, found = replica.loadRefreshRequest()
+	assert.True(t, found)
+}
+
+func TestSyncWithCurrentGenerationSharesStaleState(t *testing.T) {
+	server, err := miniredis.Run()
+	assert.Nil(t, err)
+	defer server.Close()
+
+	leader := newTestService("https://gitlab.com")
+	follower := newTestService("https://gitlab.com")
+
+	for _, s := range []*Service{leader, follower} {
+		s.config.CacheBackend = CacheBackendRedis
+		s.config.RedisUrl = "redis://" + server.Addr()
+		s.cache = s.newCacheStorage()
+	}
+
+	assert.Nil(t, leader.swapGeneration(createTestGeneration(time.Now().Add(-time.Hour), "hash")))
+	follower.syncWithCurrentGeneration()
+	assert.False(t, follower.isStale())
+
+	// the refresh of the leader failed, all instances serve the same outdated generation
+	leader.storeRefreshFailure(time.Now())
+	follower.syncWithCurrentGeneration()
+	assert.True(t, follower.isStale())
+
+	// the next refresh succeeded
+	assert.Nil(t, leader.swapGeneration(createTestGeneration(time.Now().Add(time.Second), "hash")))
+	follower.syncWithCurrentGeneration()
+	assert.False(t, follower.isStale())
+}
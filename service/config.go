@@ -1,23 +1,51 @@
 package service
 
 import (
+	"fmt"
 	"net/url"
 	"strings"
 	"time"
 
 	"github.com/pkg/errors"
+
+	"github.com/atomicptr/gitlab-composer-integration/advisories"
+	"github.com/atomicptr/gitlab-composer-integration/gitlab"
+)
+
+const (
+	CacheBackendMemory = "memory"
+	CacheBackendBolt   = "bolt"
+	CacheBackendRedis  = "redis"
 )
 
 type Config struct {
-	GitlabUrl           string        `conf:"required"`
-	GitlabToken         string        `conf:"required,noprint"`
-	CacheExpireDuration time.Duration `conf:"default:60m"`
-	CacheFilePath       string        `conf:""`
-	VendorWhitelist     []string      `conf:""`
-	Port                int           `conf:"default:4000"`
-	HttpTimeout         time.Duration `conf:"default:30s"`
-	NoCache             bool          `conf:"default:false"`
-	HttpCredentials     string        `conf:""`
+	GitlabUrl                string        `conf:"required"`
+	GitlabToken              string        `conf:"required,noprint"`
+	GitlabCaFile             string        `conf:""`
+	GitlabInsecureSkipVerify bool          `conf:"default:false"`
+	GitlabClientCertFile     string        `conf:""`
+	GitlabClientKeyFile      string        `conf:""`
+	CacheExpireDuration      time.Duration `conf:"default:60m"`
+	CacheFilePath            string        `conf:""`
+	CacheBackend             string        `conf:"default:memory"`
+	RedisUrl                 string        `conf:"noprint"`
+	VendorWhitelist          []string      `conf:""`
+	Port                     int           `conf:"default:4000"`
+	HttpTimeout              time.Duration `conf:"default:30s"`
+	NoCache                  bool          `conf:"default:false"`
+	HttpCredentials          string        `conf:""`
+	AdminCredentials         string        `conf:"noprint"`
+	StatsFilePath            string        `conf:""`
+	IndexWaitTimeout         time.Duration `conf:"default:10s"`
+	ShutdownTimeout          time.Duration `conf:"default:30s"`
+	TlsCertFile              string        `conf:""`
+	TlsKeyFile               string        `conf:""`
+	TlsMinVersion            string        `conf:"default:1.2"`
+	TlsClientCaFile          string        `conf:""`
+	TlsClientIdentities      []string      `conf:""`
+	ImpactWebhookUrl         string        `conf:"noprint"`
+	AdvisoryDatabasePath     string        `conf:""`
+	BaseUrl                  string        `conf:""`
 }
 
 // Validate the configuration
@@ -27,13 +55,89 @@ func (config *Config) Validate() error {
 		return err
 	}
 
+	switch config.CacheBackend {
+	case "", CacheBackendMemory, CacheBackendBolt:
+	case CacheBackendRedis:
+		if config.RedisUrl == "" {
+			return errors.New("the redis cache backend requires a redis url")
+		}
+	default:
+		return fmt.Errorf(
+			"unknown cache backend \"%s\", use \"%s\", \"%s\" or \"%s\"",
+			config.CacheBackend,
+			CacheBackendMemory,
+			CacheBackendBolt,
+			CacheBackendRedis,
+		)
+	}
+
+	if _, err := gitlab.NewTlsConfig(config.GetGitlabTlsOptions()); err != nil {
+		return err
+	}
+
+	if (config.TlsCertFile == "") != (config.TlsKeyFile == "") {
+		return errors.New("tls cert file and tls key file have to be set together")
+	}
+
+	if _, ok := tlsVersions[config.TlsMinVersion]; config.TlsMinVersion != "" && !ok {
+		return fmt.Errorf("unknown tls min version \"%s\", use 1.0, 1.1, 1.2 or 1.3", config.TlsMinVersion)
+	}
+
+	if config.TlsClientCaFile != "" && !config.IsTlsEnabled() {
+		return errors.New("tls client ca file requires tls cert file and tls key file")
+	}
+
 	if len(config.HttpCredentials) > 0 && !strings.Contains(config.HttpCredentials, ":") {
 		return errors.New("http credentials should be in the form of \"username:password\" or empty.")
 	}
 
+	if len(config.AdminCredentials) > 0 && !strings.Contains(config.AdminCredentials, ":") {
+		return errors.New("admin credentials should be in the form of \"username:password\" or empty.")
+	}
+
+	if config.ImpactWebhookUrl != "" {
+		webhookUrl, err := url.Parse(config.ImpactWebhookUrl)
+		if err != nil || (webhookUrl.Scheme != "http" && webhookUrl.Scheme != "https") || webhookUrl.Host == "" {
+			return errors.New("impact webhook url should be a http or https url or empty.")
+		}
+	}
+
+	if config.BaseUrl != "" {
+		baseUrl, err := url.Parse(config.BaseUrl)
+		if err != nil || (baseUrl.Scheme != "http" && baseUrl.Scheme != "https") || baseUrl.Host == "" {
+			return errors.New("base url should be a http or https url or empty.")
+		}
+	}
+
+	if config.IsAdvisoryDatabaseEnabled() {
+		if _, err := advisories.Load(config.AdvisoryDatabasePath); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+// GetGitlabTlsOptions returns the options securing the connection to Gitlab
+func (config *Config) GetGitlabTlsOptions() gitlab.TlsOptions {
+	return gitlab.TlsOptions{
+		CaFile:             config.GitlabCaFile,
+		InsecureSkipVerify: config.GitlabInsecureSkipVerify,
+		ClientCertFile:     config.GitlabClientCertFile,
+		ClientKeyFile:      config.GitlabClientKeyFile,
+	}
+}
+
+// IsTlsEnabled checks if the service should serve HTTPS
+func (config *Config) IsTlsEnabled() bool {
+	return config.TlsCertFile != "" && config.TlsKeyFile != ""
+}
+
+// IsAdvisoryDatabaseEnabled checks if security advisories should be provided
+func (config *Config) IsAdvisoryDatabaseEnabled() bool {
+	return config.AdvisoryDatabasePath != ""
+}
+
 // IsVendorAllowed checks if the given vendor is allowed
 func (config *Config) IsVendorAllowed(vendorName string) bool {
 	// vendor whitelist is empty, allow everything
@@ -53,10 +157,19 @@ func (config *Config) IsVendorAllowed(vendorName string) bool {
 
 // GetHttpCredentials returns username:password combination as username, password pair
 func (config *Config) GetHttpCredentials() (string, string) {
-	parts := strings.Split(config.HttpCredentials, ":")
+	return splitCredentials(config.HttpCredentials)
+}
+
+// GetAdminCredentials returns the credentials of the admin API as username, password pair
+func (config *Config) GetAdminCredentials() (string, string) {
+	return splitCredentials(config.AdminCredentials)
+}
+
+func splitCredentials(credentials string) (string, string) {
+	parts := strings.Split(credentials, ":")
 	if len(parts) < 2 {
 		return "", ""
 	}
 	// return everything from the first match and the rest
-	return parts[0], config.HttpCredentials[len(parts[0])+1:]
+	return parts[0], credentials[len(parts[0])+1:]
 }
@@ -26,10 +26,81 @@ func TestValidateInvalidConfigWithInvalidHttpCredentials(t *testing.T) {
 	assert.NotNil(t, config.Validate())
 }
 
+func TestValidateInvalidConfigWithUnknownCacheBackend(t *testing.T) {
+	config := Config{
+		GitlabUrl:    "https://gitlab.com",
+		CacheBackend: "floppy",
+	}
+	assert.NotNil(t, config.Validate())
+}
+
+func TestValidateInvalidConfigWithIncompleteTls(t *testing.T) {
+	config := Config{
+		GitlabUrl:   "https://gitlab.com",
+		TlsCertFile: "/etc/ssl/gci.crt",
+	}
+	assert.NotNil(t, config.Validate())
+}
+
+func TestValidateInvalidConfigWithUnknownTlsVersion(t *testing.T) {
+	config := Config{
+		GitlabUrl:     "https://gitlab.com",
+		TlsMinVersion: "2.0",
+	}
+	assert.NotNil(t, config.Validate())
+}
+
+func TestValidateInvalidConfigWithClientCaWithoutTls(t *testing.T) {
+	config := Config{
+		GitlabUrl:       "https://gitlab.com",
+		TlsClientCaFile: "/etc/ssl/ca.crt",
+	}
+	assert.NotNil(t, config.Validate())
+}
+
+func TestValidateInvalidConfigWithMissingGitlabCaFile(t *testing.T) {
+	config := Config{
+		GitlabUrl:    "https://gitlab.com",
+		GitlabCaFile: "/does/not/exist.crt",
+	}
+	assert.NotNil(t, config.Validate())
+}
+
+func TestValidateInvalidConfigWithInvalidImpactWebhookUrl(t *testing.T) {
+	config := Config{
+		GitlabUrl:        "https://gitlab.com",
+		ImpactWebhookUrl: "ftp://example.com/hook",
+	}
+	assert.NotNil(t, config.Validate())
+
+	config.ImpactWebhookUrl = "https://example.com/hook"
+	assert.Nil(t, config.Validate())
+}
+
+func TestValidateInvalidConfigWithMissingAdvisoryDatabase(t *testing.T) {
+	config := Config{
+		GitlabUrl:            "https://gitlab.com",
+		AdvisoryDatabasePath: "/does/not/exist",
+	}
+	assert.NotNil(t, config.Validate())
+}
+
+func TestValidateInvalidConfigWithInvalidBaseUrl(t *testing.T) {
+	config := Config{
+		GitlabUrl: "https://gitlab.com",
+		BaseUrl:   "composer.example.com",
+	}
+	assert.NotNil(t, config.Validate())
+
+	config.BaseUrl = "https://composer.example.com"
+	assert.Nil(t, config.Validate())
+}
+
 func TestValidate(t *testing.T) {
 	config := Config{
 		GitlabUrl:       "https://gitlab.com",
 		HttpCredentials: "username:password",
+		CacheBackend:    CacheBackendBolt,
 	}
 	assert.Nil(t, config.Validate())
 }
@@ -85,3 +156,26 @@ func TestGetHttpCredentialsComplexPassword(t *testing.T) {
 	assert.EqualValues(t, username, "Cr4zyU$3rn4m3")
 	assert.EqualValues(t, password, "P4$$W:@rd!!?")
 }
+
+func TestGetAdminCredentials(t *testing.T) {
+	config := Config{
+		HttpCredentials:  "username:password",
+		AdminCredentials: "admin:secret:password",
+	}
+
+	username, password := config.GetAdminCredentials()
+
+	assert.EqualValues(t, "admin", username)
+	assert.EqualValues(t, "secret:password", password)
+}
+
+func TestGetAdminCredentialsWithoutAdminCredentials(t *testing.T) {
+	config := Config{
+		HttpCredentials: "username:password",
+	}
+
+	username, password := config.GetAdminCredentials()
+
+	assert.Empty(t, username)
+	assert.Empty(t, password)
+}
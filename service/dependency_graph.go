@@ -0,0 +1,211 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// dependencyGraph contains the links between the published packages, it is created from the require and
+// require-dev sections of the composer.json of their default branches
+type dependencyGraph struct {
+	Packages []*dependencyNode `json:"packages"`
+	nodes    map[string]*dependencyNode
+}
+
+type dependencyNode struct {
+	Name       string            `json:"name"`
+	Requires   []*dependencyLink `json:"requires"`
+	RequiredBy []*dependencyLink `json:"requiredBy"`
+}
+
+// dependencyLink points to another package, the constraint is always the one of the requiring package
+type dependencyLink struct {
+	Name       string `json:"name"`
+	Constraint string `json:"constraint"`
+	Dev        bool   `json:"dev"`
+}
+
+// createDependencyGraph links all packages of the catalogue which require each other, requirements of packages
+// which are not published by this repository are left out
+func createDependencyGraph(catalogue []*catalogueEntry, includeDev bool) *dependencyGraph {
+	graph := &dependencyGraph{
+		Packages: make([]*dependencyNode, 0, len(catalogue)),
+		nodes:    make(map[string]*dependencyNode),
+	}
+
+	for _, entry := range catalogue {
+		node := &dependencyNode{Name: entry.Name, Requires: []*dependencyLink{}, RequiredBy: []*dependencyLink{}}
+		graph.Packages = append(graph.Packages, node)
+		graph.nodes[entry.Name] = node
+	}
+
+	sort.Slice(graph.Packages, func(i, j int) bool {
+		return graph.Packages[i].Name < graph.Packages[j].Name
+	})
+
+	for _, entry := range catalogue {
+		graph.link(entry.Name, entry.Require, false)
+
+		if includeDev {
+			graph.link(entry.Name, entry.RequireDev, true)
+		}
+	}
+
+	for _, node := range graph.Packages {
+		sortDependencyLinks(node.Requires)
+		sortDependencyLinks(node.RequiredBy)
+	}
+
+	return graph
+}
+
+func (graph *dependencyGraph) link(name string, requirements map[string]string, dev bool) {
+	for requirement, constraint := range requirements {
+		required, found := graph.nodes[requirement]
+		if !found || requirement == name {
+			continue
+		}
+
+		node := graph.nodes[name]
+		node.Requires = append(node.Requires, &dependencyLink{Name: requirement, Constraint: constraint, Dev: dev})
+		required.RequiredBy = append(required.RequiredBy, &dependencyLink{Name: name, Constraint: constraint, Dev: dev})
+	}
+}
+
+func sortDependencyLinks(links []*dependencyLink) {
+	sort.Slice(links, func(i, j int) bool {
+		if links[i].Name != links[j].Name {
+			return links[i].Name < links[j].Name
+		}
+		return !links[i].Dev && links[j].Dev
+	})
+}
+
+// dependents returns a graph which only contains the given package and all packages which require it, directly
+// or through other packages. The second return value is false if the package is unknown.
+func (graph *dependencyGraph) dependents(name string) (*dependencyGraph, bool) {
+	if _, found := graph.nodes[name]; !found {
+		return nil, false
+	}
+
+	included := map[string]bool{name: true}
+	queue := []string{name}
+
+	for len(queue) > 0 {
+		node := graph.nodes[queue[0]]
+		queue = queue[1:]
+
+		for _, link := range node.RequiredBy {
+			if !included[link.Name] {
+				included[link.Name] = true
+				queue = append(queue, link.Name)
+			}
+		}
+	}
+
+	result := &dependencyGraph{nodes: make(map[string]*dependencyNode)}
+
+	for _, node := range graph.Packages {
+		if !included[node.Name] {
+			continue
+		}
+
+		filtered := &dependencyNode{
+			Name:       node.Name,
+			Requires:   filterDependencyLinks(node.Requires, included),
+			RequiredBy: filterDependencyLinks(node.RequiredBy, included),
+		}
+
+		result.Packages = append(result.Packages, filtered)
+		result.nodes[node.Name] = filtered
+	}
+
+	return result, true
+}
+
+func filterDependencyLinks(links []*dependencyLink, included map[string]bool) []*dependencyLink {
+	filtered := []*dependencyLink{}
+	for _, link := range links {
+		if included[link.Name] {
+			filtered = append(filtered, link)
+		}
+	}
+	return filtered
+}
+
+// toDot exports the graph in the DOT language of Graphviz, requirements for development are dashed
+func (graph *dependencyGraph) toDot() string {
+	var builder strings.Builder
+
+	builder.WriteString("digraph dependencies {\n")
+	builder.WriteString("\trankdir=LR;\n")
+	builder.WriteString("\tnode [shape=box];\n")
+
+	for _, node := range graph.Packages {
+		_, _ = fmt.Fprintf(&builder, "\t%s;\n", quoteDot(node.Name))
+	}
+
+	for _, node := range graph.Packages {
+		for _, link := range node.Requires {
+			style := ""
+			if link.Dev {
+				style = ", style=dashed"
+			}
+
+			_, _ = fmt.Fprintf(
+				&builder,
+				"\t%s -> %s [label=%s%s];\n",
+				quoteDot(node.Name),
+				quoteDot(link.Name),
+				quoteDot(link.Constraint),
+				style,
+			)
+		}
+	}
+
+	builder.WriteString("}\n")
+	return builder.String()
+}
+
+// toMermaid exports the graph as Mermaid flowchart, requirements for development are dotted
+func (graph *dependencyGraph) toMermaid() string {
+	var builder strings.Builder
+
+	builder.WriteString("graph LR\n")
+
+	// package names are no valid node ids, the nodes are numbered instead
+	ids := make(map[string]string)
+	for i, node := range graph.Packages {
+		ids[node.Name] = fmt.Sprintf("p%d", i)
+		_, _ = fmt.Fprintf(&builder, "    %s[\"%s\"]\n", ids[node.Name], quoteMermaid(node.Name))
+	}
+
+	for _, node := range graph.Packages {
+		for _, link := range node.Requires {
+			arrow := "-->"
+			if link.Dev {
+				arrow = "-.->"
+			}
+
+			_, _ = fmt.Fprintf(
+				&builder,
+				"    %s %s|\"%s\"| %s\n",
+				ids[node.Name],
+				arrow,
+				quoteMermaid(link.Constraint),
+				ids[link.Name],
+			)
+		}
+	}
+
+	return builder.String()
+}
+
+func quoteDot(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+func quoteMermaid(value string) string {
+	return strings.Replace(value, `"`, "#quot;", -1)
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func createTestDependencyCatalogue() []*catalogueEntry {
+	return []*catalogueEntry{
+		{Name: "acme/logger", Require: map[string]string{"php": ">=7.2", "psr/log": "^1.0"}},
+		{Name: "acme/http-client", Require: map[string]string{"acme/logger": "^1.0"}},
+		{
+			Name:       "acme/website",
+			Require:    map[string]string{"acme/http-client": "^2.0", "acme/logger": "~1.2"},
+			RequireDev: map[string]string{"acme/testing": "*"},
+		},
+		{Name: "acme/testing", RequireDev: map[string]string{"acme/logger": "dev-master"}},
+		{Name: "acme/unrelated"},
+	}
+}
+
+func TestCreateDependencyGraph(t *testing.T) {
+	graph := createDependencyGraph(createTestDependencyCatalogue(), true)
+
+	assert.Len(t, graph.Packages, 5)
+	assert.EqualValues(t, "acme/http-client", graph.Packages[0].Name)
+
+	logger := graph.nodes["acme/logger"]
+	assert.Empty(t, logger.Requires)
+	assert.EqualValues(t, []*dependencyLink{
+		{Name: "acme/http-client", Constraint: "^1.0"},
+		{Name: "acme/testing", Constraint: "dev-master", Dev: true},
+		{Name: "acme/website", Constraint: "~1.2"},
+	}, logger.RequiredBy)
+
+	assert.EqualValues(t, []*dependencyLink{
+		{Name: "acme/http-client", Constraint: "^2.0"},
+		{Name: "acme/logger", Constraint: "~1.2"},
+		{Name: "acme/testing", Constraint: "*", Dev: true},
+	}, graph.nodes["acme/website"].Requires)
+
+	graph = createDependencyGraph(createTestDependencyCatalogue(), false)
+	assert.Len(t, graph.nodes["acme/logger"].RequiredBy, 2)
+	assert.Len(t, graph.nodes["acme/website"].Requires, 2)
+}
+
+func TestDependents(t *testing.T) {
+	graph := createDependencyGraph(createTestDependencyCatalogue(), true)
+
+	_, found := graph.dependents("acme/unknown")
+	assert.False(t, found)
+
+	dependents, found := graph.dependents("acme/http-client")
+	assert.True(t, found)
+	assert.Len(t, dependents.Packages, 2)
+	assert.EqualValues(t, "acme/http-client", dependents.Packages[0].Name)
+	assert.Empty(t, dependents.Packages[0].Requires)
+	assert.EqualValues(t, "acme/website", dependents.Packages[1].Name)
+	assert.Len(t, dependents.Packages[1].Requires, 1)
+
+	dependents, _ = graph.dependents("acme/logger")
+	assert.Len(t, dependents.Packages, 4)
+}
+
+func TestDependencyGraphToDot(t *testing.T) {
+	dependents, _ := createDependencyGraph(createTestDependencyCatalogue(), true).dependents("acme/testing")
+
+	assert.EqualValues(t, `digraph dependencies {
+	rankdir=LR;
+	node [shape=box];
+	"acme/testing";
+	"acme/website";
+	"acme/website" -> "acme/testing" [label="*", style=dashed];
+}
+`, dependents.toDot())
+
+	assert.EqualValues(t, `"a \"quoted\" \\ name"`, quoteDot(`a "quoted" \ name`))
+}
+
+func TestDependencyGraphToMermaid(t *testing.T) {
+	dependents, _ := createDependencyGraph(createTestDependencyCatalogue(), true).dependents("acme/http-client")
+
+	assert.EqualValues(t, `graph LR
+    p0["acme/http-client"]
+    p1["acme/website"]
+    p1 -->|"^2.0"| p0
+`, dependents.toMermaid())
+}
+
+func TestHandleDependenciesEndpoint(t *testing.T) {
+	s := newTestService("https://gitlab.com")
+
+	recorder := httptest.NewRecorder()
+	s.handleDependenciesEndpoint(recorder, httptest.NewRequest("GET", "/dependencies.json", nil))
+	assert.EqualValues(t, http.StatusServiceUnavailable, recorder.Code)
+
+	gen := createTestGeneration(time.Now(), "hash")
+	for _, entry := range createTestDependencyCatalogue() {
+		gen.catalogue[entry.Name] = entry
+	}
+	assert.Nil(t, s.swapGeneration(gen))
+
+	recorder = httptest.NewRecorder()
+	s.handleDependenciesEndpoint(recorder, httptest.NewRequest("GET", "/dependencies.json?package=acme/logger", nil))
+	assert.EqualValues(t, http.StatusOK, recorder.Code)
+
+	var graph dependencyGraph
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &graph))
+	assert.Len(t, graph.Packages, 4)
+
+	recorder = httptest.NewRecorder()
+	s.handleDependenciesEndpoint(recorder, httptest.NewRequest("GET", "/dependencies.json?package=acme/unknown", nil))
+	assert.EqualValues(t, http.StatusNotFound, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	s.handleDependenciesEndpoint(recorder, httptest.NewRequest("GET", "/dependencies.dot?dev=false", nil))
+	assert.EqualValues(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Header().Get("Content-Type"), "text/vnd.graphviz")
+	assert.Contains(t, recorder.Body.String(), `"acme/website" -> "acme/logger" [label="~1.2"];`)
+	assert.NotContains(t, recorder.Body.String(), "dashed")
+
+	recorder = httptest.NewRecorder()
+	s.handleDependenciesEndpoint(recorder, httptest.NewRequest("GET", "/dependencies.mmd", nil))
+	assert.EqualValues(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "graph LR")
+}
@@ -0,0 +1,360 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/atomicptr/gitlab-composer-integration/composer"
+)
+
+// points to the id of the generation which is currently served
+const currentGenerationCacheKey = "generation"
+
+// points to the id of the generation served before the current one, it is kept around for clients
+// which still work with an index of the previous generation
+const previousGenerationCacheKey = "generation-previous"
+
+const indexCacheKey = "index"
+const createdCacheKey = "created"
+const scannedCacheKey = "scanned"
+const degradedCacheKey = "degraded"
+const catalogueCacheKey = "catalogue"
+
+// generation is a complete, immutable snapshot of the repository built by a single refresh. It is only
+// written to the cache once it is complete and then swapped in atomically.
+type generation struct {
+	id      string
+	created time.Time
+	// scanned is the time of the full scan the generation is based on, single projects might have been
+	// refreshed since then
+	scanned   time.Time
+	index     []byte
+	providers map[string][]byte
+	hashes    map[string]string
+	catalogue map[string]*catalogueEntry
+	degraded  []*degradedPackage
+	report    *scanReport
+}
+
+func newGeneration(created time.Time) *generation {
+	return &generation{
+		id:        strconv.FormatInt(created.UnixNano(), 10),
+		created:   created,
+		scanned:   created,
+		providers: make(map[string][]byte),
+		hashes:    make(map[string]string),
+		catalogue: make(map[string]*catalogueEntry),
+	}
+}
+
+// removeProject removes all packages of the given project from the generation
+func (gen *generation) removeProject(projectId int) {
+	for name, entry := range gen.catalogue {
+		if entry.ProjectId == projectId {
+			delete(gen.catalogue, name)
+			delete(gen.providers, name)
+			delete(gen.hashes, name)
+		}
+	}
+
+	degraded := gen.degraded[:0]
+	for _, item := range gen.degraded {
+		if item.ProjectId != projectId {
+			degraded = append(degraded, item)
+		}
+	}
+	gen.degraded = degraded
+}
+
+// createIndex creates the packages.json of the generation, composer is told to query the security advisories
+// API if enabled
+func (gen *generation) createIndex(securityAdvisories bool) ([]byte, error) {
+	providers := make(map[string]composer.Provider)
+	for name, hash := range gen.hashes {
+		providers[name] = composer.Provider{Sha256: hash}
+	}
+
+	composerRepository := composer.Repository{
+		Packages:     []struct{}{},
+		NotifyBatch:  "/notify",
+		ProvidersUrl: "/p?package=%package%&hash=%hash%",
+		Providers:    providers,
+		SearchUrl:    "/search.json?q=%query%&type=%type%",
+		ListUrl:      "/packages/list.json",
+	}
+
+	if securityAdvisories {
+		composerRepository.SecurityAdvisories = &composer.SecurityAdvisories{ApiUrl: securityAdvisoriesApiUrl}
+	}
+
+	return composerRepository.ToJson()
+}
+
+// sortedCatalogue returns all published packages sorted by name
+func (gen *generation) sortedCatalogue() []*catalogueEntry {
+	catalogue := make([]*catalogueEntry, 0, len(gen.catalogue))
+	for _, entry := range gen.catalogue {
+		catalogue = append(catalogue, entry)
+	}
+
+	sort.Slice(catalogue, func(i, j int) bool {
+		return catalogue[i].Name < catalogue[j].Name
+	})
+
+	return catalogue
+}
+
+func getGenerationCacheIdentifier(generationId, key string) string {
+	return fmt.Sprintf("%s/%s", generationId, key)
+}
+
+// swapGeneration stores all entries of the given generation and makes it the current one afterwards,
+// the generation before the previous one will be removed
+func (s *Service) swapGeneration(gen *generation) error {
+	catalogue, err := json.Marshal(gen.sortedCatalogue())
+	if err != nil {
+		return errors.Wrapf(err, "could not encode catalogue of generation %s", gen.id)
+	}
+
+	entries := map[string][]byte{
+		createdCacheKey:   []byte(strconv.FormatInt(gen.created.Unix(), 10)),
+		scannedCacheKey:   []byte(strconv.FormatInt(gen.scanned.Unix(), 10)),
+		indexCacheKey:     gen.index,
+		catalogueCacheKey: catalogue,
+	}
+
+	if len(gen.degraded) > 0 {
+		degraded, err := json.Marshal(gen.degraded)
+		if err != nil {
+			return errors.Wrapf(err, "could not encode degraded packages of generation %s", gen.id)
+		}
+		entries[degradedCacheKey] = degraded
+	}
+
+	for name, data := range gen.providers {
+		entries[getProjectCacheIdentifier(name)] = data
+	}
+
+	for name, hash := range gen.hashes {
+		entries[getProjectHashIdentifier(name)] = []byte(hash)
+	}
+
+	values := make(map[string][]byte, len(entries))
+	for key, value := range entries {
+		values[getGenerationCacheIdentifier(gen.id, key)] = value
+	}
+
+	if err := s.cache.SetAll(values); err != nil {
+		return errors.Wrapf(err, "could not store generation %s", gen.id)
+	}
+
+	outdatedId, _ := s.previousGenerationId()
+	previousId, hasPrevious := s.currentGenerationId()
+
+	// the generation is complete, from now on it will be served
+	err = s.cache.Set(currentGenerationCacheKey, []byte(gen.id))
+	if err != nil {
+		return errors.Wrapf(err, "could not activate generation %s", gen.id)
+	}
+
+	if hasPrevious {
+		err = s.cache.Set(previousGenerationCacheKey, []byte(previousId))
+		if err != nil {
+			return errors.Wrapf(err, "could not keep generation %s", previousId)
+		}
+	}
+
+	if outdatedId != "" && outdatedId != previousId {
+		err = s.cache.DeletePrefix(getGenerationCacheIdentifier(outdatedId, ""))
+		if err != nil {
+			s.logger.Println(errors.Wrapf(err, "could not remove outdated generation %s", outdatedId))
+		}
+	}
+
+	// the instance swapping the generation updates its state by itself
+	s.setSyncedGenerationId(gen.id)
+	return nil
+}
+
+// dropPreviousGeneration removes the generation served before the current one, clients still using its index
+// have to fetch the current one
+func (s *Service) dropPreviousGeneration() error {
+	previousId, found := s.previousGenerationId()
+	if !found {
+		return nil
+	}
+
+	if err := s.cache.Delete(previousGenerationCacheKey); err != nil {
+		return errors.Wrapf(err, "could not remove generation %s", previousId)
+	}
+
+	return errors.Wrapf(
+		s.cache.DeletePrefix(getGenerationCacheIdentifier(previousId, "")),
+		"could not remove generation %s",
+		previousId,
+	)
+}
+
+func (s *Service) currentGenerationId() (string, bool) {
+	return s.getGenerationId(currentGenerationCacheKey)
+}
+
+func (s *Service) previousGenerationId() (string, bool) {
+	return s.getGenerationId(previousGenerationCacheKey)
+}
+
+func (s *Service) getGenerationId(key string) (string, bool) {
+	generationId, found := s.getFromCache(key)
+	if !found {
+		return "", false
+	}
+	return string(generationId), true
+}
+
+// getFromGeneration reads the key from the given generation
+func (s *Service) getFromGeneration(generationId, key string) ([]byte, bool) {
+	return s.getFromCache(getGenerationCacheIdentifier(generationId, key))
+}
+
+// getFromCache reads the key from the cache storage, errors are treated like missing keys
+func (s *Service) getFromCache(key string) ([]byte, bool) {
+	value, found, err := s.cache.Get(key)
+	if err != nil {
+		s.logger.Println(errors.Wrapf(err, "could not read %s from cache", key))
+		return nil, false
+	}
+	return value, found
+}
+
+// generationCreated returns the time the given generation was created at
+func (s *Service) generationCreated(generationId string) (time.Time, bool) {
+	return s.getGenerationTime(generationId, createdCacheKey)
+}
+
+// generationScanned returns the time of the full scan the given generation is based on
+func (s *Service) generationScanned(generationId string) (time.Time, bool) {
+	scanned, found := s.getGenerationTime(generationId, scannedCacheKey)
+	if !found {
+		// generations created before single projects could be refreshed
+		return s.generationCreated(generationId)
+	}
+	return scanned, true
+}
+
+func (s *Service) getGenerationTime(generationId, key string) (time.Time, bool) {
+	value, found := s.getFromGeneration(generationId, key)
+	if !found {
+		return time.Time{}, false
+	}
+
+	timestamp, err := strconv.ParseInt(string(value), 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return time.Unix(timestamp, 0), true
+}
+
+// isGenerationExpired returns true if there is no current generation or if its full scan is older than the
+// configured cache expire duration
+func (s *Service) isGenerationExpired() bool {
+	generationId, found := s.currentGenerationId()
+	if !found {
+		return true
+	}
+
+	scanned, found := s.generationScanned(generationId)
+	if !found {
+		return true
+	}
+
+	return time.Since(scanned) > s.config.CacheExpireDuration
+}
+
+// currentCatalogue returns all packages published by the current generation
+func (s *Service) currentCatalogue() ([]*catalogueEntry, bool) {
+	generationId, found := s.currentGenerationId()
+	if !found {
+		return nil, false
+	}
+
+	return s.loadCatalogue(generationId)
+}
+
+func (s *Service) loadCatalogue(generationId string) ([]*catalogueEntry, bool) {
+	data, found := s.getFromGeneration(generationId, catalogueCacheKey)
+	if !found {
+		return nil, false
+	}
+
+	var catalogue []*catalogueEntry
+	if err := json.Unmarshal(data, &catalogue); err != nil {
+		s.logger.Println(errors.Wrap(err, "could not read catalogue"))
+		return nil, false
+	}
+
+	return catalogue, true
+}
+
+// deriveGeneration creates a new generation containing all packages of the current one, this way single
+// projects can be refreshed without scanning everything
+func (s *Service) deriveGeneration(created time.Time) (*generation, error) {
+	generationId, found := s.currentGenerationId()
+	if !found {
+		return nil, errors.New("there is no generation yet")
+	}
+
+	catalogue, found := s.loadCatalogue(generationId)
+	if !found {
+		return nil, errors.Errorf("generation %s has no catalogue", generationId)
+	}
+
+	gen := newGeneration(created)
+	gen.scanned, _ = s.generationScanned(generationId)
+	gen.degraded = s.currentDegradedPackages()
+
+	for _, entry := range catalogue {
+		data, found := s.getFromGeneration(generationId, getProjectCacheIdentifier(entry.Name))
+		if !found {
+			return nil, errors.Errorf("package %s is missing in generation %s", entry.Name, generationId)
+		}
+
+		hash, found := s.getFromGeneration(generationId, getProjectHashIdentifier(entry.Name))
+		if !found {
+			return nil, errors.Errorf("hash of %s is missing in generation %s", entry.Name, generationId)
+		}
+
+		gen.providers[entry.Name] = data
+		gen.hashes[entry.Name] = string(hash)
+		gen.catalogue[entry.Name] = entry
+	}
+
+	return gen, nil
+}
+
+// currentDegradedPackages returns the packages of the current generation which are published with their
+// last known good version
+func (s *Service) currentDegradedPackages() []*degradedPackage {
+	generationId, found := s.currentGenerationId()
+	if !found {
+		return nil
+	}
+
+	data, found := s.getFromGeneration(generationId, degradedCacheKey)
+	if !found {
+		return nil
+	}
+
+	var degraded []*degradedPackage
+	if err := json.Unmarshal(data, &degraded); err != nil {
+		s.logger.Println(errors.Wrap(err, "could not read degraded packages"))
+		return nil
+	}
+
+	return degraded
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func createTestGeneration(created time.Time, hash string) *generation {
+	gen := newGeneration(created)
+	gen.index = []byte(`{"packages": []}`)
+	gen.providers["atomicptr/test"] = []byte(`{"packages": {}}`)
+	gen.hashes["atomicptr/test"] = hash
+	return gen
+}
+
+func TestSwapGeneration(t *testing.T) {
+	s := newTestService("https://gitlab.com")
+
+	first := createTestGeneration(time.Now().Add(-2*time.Hour), "first")
+	second := createTestGeneration(time.Now().Add(-time.Hour), "second")
+	third := createTestGeneration(time.Now(), "third")
+
+	assert.Nil(t, s.swapGeneration(first))
+	currentId, _ := s.currentGenerationId()
+	assert.EqualValues(t, first.id, currentId)
+
+	assert.Nil(t, s.swapGeneration(second))
+	assert.Nil(t, s.swapGeneration(third))
+
+	currentId, _ = s.currentGenerationId()
+	previousId, _ := s.previousGenerationId()
+	assert.EqualValues(t, third.id, currentId)
+	assert.EqualValues(t, second.id, previousId)
+
+	// the first generation is outdated and should be gone
+	_, found := s.getFromGeneration(first.id, indexCacheKey)
+	assert.False(t, found)
+
+	_, found = s.getFromGeneration(second.id, indexCacheKey)
+	assert.True(t, found)
+}
+
+func TestIsGenerationExpired(t *testing.T) {
+	s := newTestService("https://gitlab.com")
+
+	assert.True(t, s.isGenerationExpired())
+
+	assert.Nil(t, s.swapGeneration(createTestGeneration(time.Now().Add(-2*time.Hour), "old")))
+	assert.True(t, s.isGenerationExpired())
+
+	assert.Nil(t, s.swapGeneration(createTestGeneration(time.Now(), "new")))
+	assert.False(t, s.isGenerationExpired())
+}
+
+func TestHandleProviderEndpointPreviousGeneration(t *testing.T) {
+	s := newTestService("https://gitlab.com")
+
+	assert.Nil(t, s.swapGeneration(createTestGeneration(time.Now().Add(-time.Hour), "previous")))
+	assert.Nil(t, s.swapGeneration(createTestGeneration(time.Now(), "current")))
+
+	for _, hash := range []string{"current", "previous"} {
+		recorder := httptest.NewRecorder()
+		s.handleProviderEndpoint(
+			recorder,
+			httptest.NewRequest("GET", "/p?package=atomicptr/test&hash="+hash, nil),
+		)
+		assert.EqualValues(t, http.StatusOK, recorder.Code)
+	}
+
+	recorder := httptest.NewRecorder()
+	s.handleProviderEndpoint(recorder, httptest.NewRequest("GET", "/p?package=atomicptr/test&hash=unknown", nil))
+	assert.EqualValues(t, http.StatusNotFound, recorder.Code)
+}
+
+func TestHandleProviderEndpointStale(t *testing.T) {
+	s := newTestService("https://gitlab.com")
+
+	assert.Nil(t, s.swapGeneration(createTestGeneration(time.Now(), "current")))
+	s.setStale(true)
+
+	recorder := httptest.NewRecorder()
+	s.handleProviderEndpoint(recorder, httptest.NewRequest("GET", "/p?package=atomicptr/test&hash=current", nil))
+
+	assert.EqualValues(t, http.StatusOK, recorder.Code)
+	assert.NotEmpty(t, recorder.Header().Get("Warning"))
+}
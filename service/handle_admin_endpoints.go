@@ -0,0 +1,180 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// handleAdminRefreshEndpoint queues a full refresh or, if a project id or package name is given, the refresh
+// of a single project
+func (s *Service) handleAdminRefreshEndpoint(writer http.ResponseWriter, request *http.Request) {
+	s.logger.Printf("Request to \"%s\" from %s (%s)\n", request.URL, request.UserAgent(), request.RemoteAddr)
+
+	if !requireMethod(writer, request, http.MethodPost) {
+		return
+	}
+
+	query := request.URL.Query()
+
+	var projectIds []int
+
+	if project := query.Get("project"); project != "" {
+		projectId, err := strconv.Atoi(project)
+		if err != nil {
+			http.Error(writer, "project has to be the id of a Gitlab project", http.StatusBadRequest)
+			return
+		}
+		projectIds = append(projectIds, projectId)
+	}
+
+	if packageName := query.Get("package"); packageName != "" {
+		projectId, found := s.findScannedPackageProjectId(packageName)
+		if !found {
+			http.Error(writer, "package "+packageName+" is unknown", http.StatusNotFound)
+			return
+		}
+		projectIds = append(projectIds, projectId)
+	}
+
+	refresh, err := s.queueRefresh(len(projectIds) == 0, projectIds...)
+	if err != nil {
+		s.logger.Println(err)
+		http.Error(writer, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	s.respondJson(writer, http.StatusAccepted, refresh)
+}
+
+// handleAdminFlushEndpoint removes all generations but the current one and queues a full refresh, the current
+// generation is served until the refresh swaps in a new one and removed afterwards. Last known good versions,
+// uids and the leadership are kept.
+func (s *Service) handleAdminFlushEndpoint(writer http.ResponseWriter, request *http.Request) {
+	s.logger.Printf("Request to \"%s\" from %s (%s)\n", request.URL, request.UserAgent(), request.RemoteAddr)
+
+	if !requireMethod(writer, request, http.MethodPost) {
+		return
+	}
+
+	if err := s.dropPreviousGeneration(); err != nil {
+		s.logger.Println(errors.Wrap(err, "could not flush cache"))
+		http.Error(writer, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	s.logger.Println("cache flushed via admin API")
+
+	refresh, err := s.queueRefreshRequest(&refreshRequest{Full: true, Flush: true})
+	if err != nil {
+		s.logger.Println(err)
+		http.Error(writer, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	s.respondJson(writer, http.StatusAccepted, refresh)
+}
+
+// handleAdminPackagesEndpoint lists all published packages with their source project and versions
+func (s *Service) handleAdminPackagesEndpoint(writer http.ResponseWriter, request *http.Request) {
+	s.logger.Printf("Request to \"%s\" from %s (%s)\n", request.URL, request.UserAgent(), request.RemoteAddr)
+
+	if !requireMethod(writer, request, http.MethodGet) {
+		return
+	}
+
+	catalogue, found := s.currentCatalogue()
+	if !found {
+		respondIndexUnavailable(writer)
+		return
+	}
+
+	s.respondJson(writer, http.StatusOK, struct {
+		Packages []*catalogueEntry `json:"packages"`
+	}{catalogue})
+}
+
+// handleAdminProgressEndpoint shows the progress of the running (or the last) scan and pending refreshes
+func (s *Service) handleAdminProgressEndpoint(writer http.ResponseWriter, request *http.Request) {
+	s.logger.Printf("Request to \"%s\" from %s (%s)\n", request.URL, request.UserAgent(), request.RemoteAddr)
+
+	if !requireMethod(writer, request, http.MethodGet) {
+		return
+	}
+
+	response := struct {
+		Scan    *scanProgress   `json:"scan"`
+		Pending *refreshRequest `json:"pending"`
+	}{}
+
+	response.Scan, _ = s.loadScanProgress()
+	response.Pending, _ = s.loadRefreshRequest()
+
+	s.respondJson(writer, http.StatusOK, response)
+}
+
+// findPackageProjectId returns the id of the project the given package is published from
+func (s *Service) findPackageProjectId(packageName string) (int, bool) {
+	catalogue, found := s.currentCatalogue()
+	if !found {
+		return 0, false
+	}
+
+	for _, entry := range catalogue {
+		if entry.Name == packageName {
+			return entry.ProjectId, true
+		}
+	}
+
+	return 0, false
+}
+
+// findScannedPackageProjectId returns the id of the project the given package is published from, packages which
+// could not be published are looked up in the scan report
+func (s *Service) findScannedPackageProjectId(packageName string) (int, bool) {
+	if projectId, found := s.findPackageProjectId(packageName); found {
+		return projectId, true
+	}
+
+	report, found := s.loadScanReport()
+	if !found {
+		return 0, false
+	}
+
+	scan, found := report.findPackage(packageName)
+	if !found {
+		return 0, false
+	}
+
+	return scan.ProjectId, true
+}
+
+func (s *Service) respondJson(writer http.ResponseWriter, statusCode int, response interface{}) {
+	data, err := json.Marshal(response)
+	if err != nil {
+		s.logger.Println(errors.Wrap(err, "could not transform response to json"))
+		http.Error(writer, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(statusCode)
+
+	_, err = writer.Write(data)
+	if err != nil {
+		s.logger.Println(err)
+	}
+}
+
+// requireMethod responds with 405 if the request does not use the given method
+func requireMethod(writer http.ResponseWriter, request *http.Request, method string) bool {
+	if request.Method == method {
+		return true
+	}
+
+	writer.Header().Set("Allow", method)
+	http.Error(writer, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	return false
+}
@@ -0,0 +1,303 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func createTestCatalogueGeneration(created time.Time) *generation {
+	gen := createTestGeneration(created, "hash")
+	gen.catalogue["atomicptr/test"] = &catalogueEntry{
+		Name:      "atomicptr/test",
+		ProjectId: 42,
+		Project:   "atomicptr/test",
+		Versions:  []string{"dev-master"},
+	}
+	return gen
+}
+
+func TestHandleAdminFunc(t *testing.T) {
+	handler := func(writer http.ResponseWriter, _ *http.Request) {}
+
+	s := newTestService("https://gitlab.com")
+	s.handleAdminFunc("/admin/test", handler)
+
+	recorder := httptest.NewRecorder()
+	s.httpHandler.ServeHTTP(recorder, httptest.NewRequest("GET", "/admin/test", nil))
+	assert.EqualValues(t, http.StatusForbidden, recorder.Code)
+
+	s = newTestService("https://gitlab.com")
+	s.config.HttpCredentials = "username:password"
+	s.handleAdminFunc("/admin/test", handler)
+
+	request := httptest.NewRequest("GET", "/admin/test", nil)
+	request.SetBasicAuth("username", "password")
+
+	recorder = httptest.NewRecorder()
+	s.httpHandler.ServeHTTP(recorder, request)
+	assert.EqualValues(t, http.StatusForbidden, recorder.Code)
+
+	s = newTestService("https://gitlab.com")
+	s.config.AdminCredentials = "admin:password"
+	s.handleAdminFunc("/admin/test", handler)
+
+	recorder = httptest.NewRecorder()
+	s.httpHandler.ServeHTTP(recorder, httptest.NewRequest("GET", "/admin/test", nil))
+	assert.EqualValues(t, http.StatusUnauthorized, recorder.Code)
+
+	request = httptest.NewRequest("GET", "/admin/test", nil)
+	request.SetBasicAuth("admin", "password")
+
+	recorder = httptest.NewRecorder()
+	s.httpHandler.ServeHTTP(recorder, request)
+	assert.EqualValues(t, http.StatusOK, recorder.Code)
+}
+
+func TestHandleAdminRefreshEndpoint(t *testing.T) {
+	s := newTestService("https://gitlab.com")
+
+	recorder := httptest.NewRecorder()
+	s.handleAdminRefreshEndpoint(recorder, httptest.NewRequest("GET", "/admin/refresh", nil))
+	assert.EqualValues(t, http.StatusMethodNotAllowed, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	s.handleAdminRefreshEndpoint(recorder, httptest.NewRequest("POST", "/admin/refresh", nil))
+	assert.EqualValues(t, http.StatusAccepted, recorder.Code)
+
+	request, found := s.loadRefreshRequest()
+	assert.True(t, found)
+	assert.True(t, request.Full)
+	assert.Len(t, s.refreshTrigger, 1)
+}
+
+func TestHandleAdminRefreshEndpointSingleProject(t *testing.T) {
+	s := newTestService("https://gitlab.com")
+	assert.Nil(t, s.swapGeneration(createTestCatalogueGeneration(time.Now())))
+
+	recorder := httptest.NewRecorder()
+	s.handleAdminRefreshEndpoint(recorder, httptest.NewRequest("POST", "/admin/refresh?project=abc", nil))
+	assert.EqualValues(t, http.StatusBadRequest, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	s.handleAdminRefreshEndpoint(recorder, httptest.NewRequest("POST", "/admin/refresh?package=atomicptr/unknown", nil))
+	assert.EqualValues(t, http.StatusNotFound, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	s.handleAdminRefreshEndpoint(recorder, httptest.NewRequest("POST", "/admin/refresh?project=7", nil))
+	assert.EqualValues(t, http.StatusAccepted, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	s.handleAdminRefreshEndpoint(recorder, httptest.NewRequest("POST", "/admin/refresh?package=atomicptr/test", nil))
+	assert.EqualValues(t, http.StatusAccepted, recorder.Code)
+
+	request, found := s.loadRefreshRequest()
+	assert.True(t, found)
+	assert.False(t, request.Full)
+	assert.EqualValues(t, []int{7, 42}, request.ProjectIds)
+}
+
+func TestHandleAdminRefreshEndpointUnpublishedPackage(t *testing.T) {
+	s := newTestService("https://gitlab.com")
+	assert.Nil(t, s.swapGeneration(createTestCatalogueGeneration(time.Now())))
+	s.storeScanReport(&scanReport{Projects: []*projectReport{
+		{ProjectId: 44, Project: "atomicptr/broken", Package: "atomicptr/broken", Status: "invalid-schema"},
+	}})
+
+	// the package is missing because its composer.json is invalid
+	recorder := httptest.NewRecorder()
+	s.handleAdminRefreshEndpoint(recorder, httptest.NewRequest("POST", "/admin/refresh?package=atomicptr/broken", nil))
+	assert.EqualValues(t, http.StatusAccepted, recorder.Code)
+
+	request, found := s.loadRefreshRequest()
+	assert.True(t, found)
+	assert.EqualValues(t, []int{44}, request.ProjectIds)
+}
+
+func TestHandleAdminFlushEndpoint(t *testing.T) {
+	composerJson := `{"name": "atomicptr/test"}`
+
+	server := createLastKnownGoodTestServer(&composerJson)
+	defer server.Close()
+
+	s := newTestService(server.URL)
+	assert.Nil(t, s.swapGeneration(createTestCatalogueGeneration(time.Now().Add(-time.Minute))))
+	assert.Nil(t, s.swapGeneration(createTestCatalogueGeneration(time.Now())))
+	assert.Nil(t, s.cache.Set(getUidCacheIdentifier(42, "dev-master"), []byte("1")))
+
+	flushedId, _ := s.currentGenerationId()
+	outdatedId, _ := s.previousGenerationId()
+
+	recorder := httptest.NewRecorder()
+	s.handleAdminFlushEndpoint(recorder, httptest.NewRequest("POST", "/admin/flush", nil))
+	assert.EqualValues(t, http.StatusAccepted, recorder.Code)
+
+	// the current generation is served until the refresh is done
+	currentId, found := s.currentGenerationId()
+	assert.True(t, found)
+	assert.EqualValues(t, flushedId, currentId)
+
+	_, found = s.previousGenerationId()
+	assert.False(t, found)
+	_, found = s.getFromGeneration(outdatedId, indexCacheKey)
+	assert.False(t, found)
+
+	request, found := s.loadRefreshRequest()
+	assert.True(t, found)
+	assert.True(t, request.Full)
+	assert.True(t, request.Flush)
+
+	s.updateCache()
+
+	currentId, _ = s.currentGenerationId()
+	assert.NotEqual(t, flushedId, currentId)
+
+	_, found = s.previousGenerationId()
+	assert.False(t, found)
+	_, found = s.getFromGeneration(flushedId, indexCacheKey)
+	assert.False(t, found)
+
+	_, found = s.getFromCache(getUidCacheIdentifier(42, "dev-master"))
+	assert.True(t, found)
+}
+
+func TestHandleAdminPackagesEndpoint(t *testing.T) {
+	s := newTestService("https://gitlab.com")
+
+	recorder := httptest.NewRecorder()
+	s.handleAdminPackagesEndpoint(recorder, httptest.NewRequest("GET", "/admin/packages", nil))
+	assert.EqualValues(t, http.StatusServiceUnavailable, recorder.Code)
+
+	assert.Nil(t, s.swapGeneration(createTestCatalogueGeneration(time.Now())))
+
+	recorder = httptest.NewRecorder()
+	s.handleAdminPackagesEndpoint(recorder, httptest.NewRequest("GET", "/admin/packages", nil))
+	assert.EqualValues(t, http.StatusOK, recorder.Code)
+
+	var response struct {
+		Packages []*catalogueEntry `json:"packages"`
+	}
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.Len(t, response.Packages, 1)
+	assert.EqualValues(t, 42, response.Packages[0].ProjectId)
+	assert.EqualValues(t, []string{"dev-master"}, response.Packages[0].Versions)
+}
+
+func TestHandleAdminProgressEndpoint(t *testing.T) {
+	s := newTestService("https://gitlab.com")
+
+	s.startScanProgress(scanKindFull)
+	s.updateScanProgress(50)
+
+	recorder := httptest.NewRecorder()
+	s.handleAdminProgressEndpoint(recorder, httptest.NewRequest("GET", "/admin/progress", nil))
+	assert.EqualValues(t, http.StatusOK, recorder.Code)
+
+	var response struct {
+		Scan *scanProgress `json:"scan"`
+	}
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.True(t, response.Scan.Running)
+	assert.EqualValues(t, scanKindFull, response.Scan.Kind)
+	assert.EqualValues(t, 50, response.Scan.Scanned)
+
+	s.finishScanProgress(nil)
+
+	progress, found := s.loadScanProgress()
+	assert.True(t, found)
+	assert.False(t, progress.Running)
+	assert.NotNil(t, progress.FinishedAt)
+}
+
+func TestUpdateCacheRefreshesSingleProject(t *testing.T) {
+	composerJson := `{"name": "atomicptr/test"}`
+
+	server := createLastKnownGoodTestServer(&composerJson)
+	defer server.Close()
+
+	s := newTestService(server.URL)
+	s.updateCache()
+
+	firstId, found := s.currentGenerationId()
+	assert.True(t, found)
+
+	catalogue, _ := s.currentCatalogue()
+	assert.Len(t, catalogue, 1)
+	assert.False(t, catalogue[0].Degraded)
+
+	composerJson = `{"name": "atomicptr/test",`
+
+	_, err := s.queueRefresh(false, 1)
+	assert.Nil(t, err)
+	s.updateCache()
+
+	secondId, _ := s.currentGenerationId()
+	assert.NotEqual(t, firstId, secondId)
+	assert.False(t, s.isGenerationExpired())
+
+	catalogue, _ = s.currentCatalogue()
+	assert.Len(t, catalogue, 1)
+	assert.True(t, catalogue[0].Degraded)
+
+	report, _ := s.loadScanReport()
+	assert.Len(t, report.Projects, 1)
+	assert.EqualValues(t, "invalid-json", report.Projects[0].Status)
+	assert.True(t, report.Projects[0].Degraded)
+
+	_, found = s.loadRefreshRequest()
+	assert.False(t, found)
+}
+
+func TestRefreshProjectsRemovesDeletedProject(t *testing.T) {
+	composerJson := `{"name": "atomicptr/other"}`
+
+	server := createLastKnownGoodTestServer(&composerJson)
+	defer server.Close()
+
+	s := newTestService(server.URL)
+	assert.Nil(t, s.swapGeneration(createTestCatalogueGeneration(time.Now())))
+	s.storeLastKnownGood(42, &lastKnownGood{Name: "atomicptr/test"})
+
+	// project 42 is unknown to the test server
+	assert.Nil(t, s.refreshProjects(context.Background(), []int{42}))
+
+	catalogue, found := s.currentCatalogue()
+	assert.True(t, found)
+	assert.Empty(t, catalogue)
+
+	_, found = s.loadLastKnownGood(42)
+	assert.False(t, found)
+}
+
+func TestQueueRefreshConcurrently(t *testing.T) {
+	s := newTestService("https://gitlab.com")
+
+	var wg sync.WaitGroup
+	for projectId := 1; projectId <= 20; projectId++ {
+		wg.Add(1)
+		go func(projectId int) {
+			defer wg.Done()
+			_, err := s.queueRefresh(false, projectId)
+			assert.Nil(t, err)
+		}(projectId)
+	}
+	wg.Wait()
+
+	request, found := s.takeRefreshRequest()
+	assert.True(t, found)
+	assert.Len(t, request.ProjectIds, 20)
+
+	_, found = s.takeRefreshRequest()
+	assert.False(t, found)
+}
+
+func TestRefreshProjectsWithoutGeneration(t *testing.T) {
+	s := newTestService("https://gitlab.com")
+	assert.NotNil(t, s.refreshProjects(context.Background(), []int{1}))
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// handleDependenciesEndpoint serves the dependency graph of all published packages as JSON (/dependencies.json),
+// DOT (/dependencies.dot) or Mermaid (/dependencies.mmd). With ?package=vendor/name only the package and the
+// packages depending on it are included, with ?dev=false requirements for development are left out.
+func (s *Service) handleDependenciesEndpoint(writer http.ResponseWriter, request *http.Request) {
+	s.logger.Printf("Request to \"%s\" from %s (%s)\n", request.URL, request.UserAgent(), request.RemoteAddr)
+
+	catalogue, found := s.currentCatalogue()
+	if !found {
+		respondIndexUnavailable(writer)
+		return
+	}
+
+	query := request.URL.Query()
+
+	graph := createDependencyGraph(catalogue, query.Get("dev") != "false")
+
+	if packageName := query.Get("package"); packageName != "" {
+		graph, found = graph.dependents(packageName)
+		if !found {
+			http.Error(writer, "package "+packageName+" is not published", http.StatusNotFound)
+			return
+		}
+	}
+
+	s.setStaleWarning(writer)
+
+	var content string
+
+	switch {
+	case strings.HasSuffix(request.URL.Path, ".dot"):
+		writer.Header().Set("Content-Type", "text/vnd.graphviz; charset=utf-8")
+		content = graph.toDot()
+	case strings.HasSuffix(request.URL.Path, ".mmd"):
+		writer.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		content = graph.toMermaid()
+	default:
+		s.respondJson(writer, http.StatusOK, graph)
+		return
+	}
+
+	if _, err := writer.Write([]byte(content)); err != nil {
+		s.logger.Println(errors.Wrap(err, "could not write dependency graph"))
+	}
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/atomicptr/gitlab-composer-integration/composer"
+)
+
+const docsUrlPrefix = "/docs/"
+
+// the files of a version can change if its tag is moved, clients have to revalidate them
+const docsCacheControl = "private, no-cache"
+
+// handleDocsEndpoint serves the readme, changelog or license of a package version at
+// /docs/<vendor>/<name>/<version>/<README|CHANGELOG|LICENSE>, this way they can be read without access to Gitlab
+func (s *Service) handleDocsEndpoint(writer http.ResponseWriter, request *http.Request) {
+	s.logger.Printf("Request to \"%s\" from %s (%s)\n", request.URL, request.UserAgent(), request.RemoteAddr)
+
+	// versions (i.e. tags) may contain an escaped "/", so the path is split before it is unescaped
+	parts, err := splitEscapedPath(strings.TrimPrefix(request.URL.EscapedPath(), docsUrlPrefix))
+	if err != nil || len(parts) != 4 {
+		http.NotFound(writer, request)
+		return
+	}
+
+	packageName := parts[0] + "/" + parts[1]
+	version := parts[2]
+	kind := strings.ToLower(parts[3])
+
+	if _, ok := packageFileNames[kind]; !ok {
+		http.NotFound(writer, request)
+		return
+	}
+
+	projectId, ref, found := s.findVersionReference(packageName, version)
+	if !found {
+		http.Error(writer, "version "+version+" of "+packageName+" is not published", http.StatusNotFound)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(request.Context(), readmeTimeout)
+	defer cancel()
+
+	file, err := s.packageFile(ctx, projectId, ref, kind)
+	if err != nil {
+		s.logger.Println(errors.Wrapf(err, "could not load %s of %s %s", kind, packageName, version))
+		http.Error(writer, http.StatusText(http.StatusBadGateway), http.StatusBadGateway)
+		return
+	}
+
+	if !file.Found {
+		http.Error(writer, packageName+" "+version+" has no "+strings.ToUpper(kind), http.StatusNotFound)
+		return
+	}
+
+	writer.Header().Set("Content-Type", packageFileContentType(file.Path))
+	writer.Header().Set("Cache-Control", docsCacheControl)
+	writer.Header().Set("ETag", strconv.Quote(ref+"-"+kind))
+
+	// handles If-None-Match for us
+	http.ServeContent(writer, request, "", time.Time{}, bytes.NewReader(file.Content))
+}
+
+// findVersionReference returns the project and the commit of the given version of a package
+func (s *Service) findVersionReference(packageName, version string) (int, string, bool) {
+	generationId, found := s.currentGenerationId()
+	if !found {
+		return 0, "", false
+	}
+
+	projectId, found := s.findPackageProjectId(packageName)
+	if !found {
+		return 0, "", false
+	}
+
+	data, found := s.getFromGeneration(generationId, getProjectCacheIdentifier(packageName))
+	if !found {
+		return 0, "", false
+	}
+
+	var provider composer.ProviderRepository
+	if err := json.Unmarshal(data, &provider); err != nil {
+		s.logger.Println(errors.Wrapf(err, "could not read package %s", packageName))
+		return 0, "", false
+	}
+
+	info, found := provider.Packages[packageName][version]
+	if !found || info.Source.Reference == "" {
+		return 0, "", false
+	}
+
+	return projectId, info.Source.Reference, true
+}
+
+// splitEscapedPath splits the escaped path into its unescaped segments
+func splitEscapedPath(escapedPath string) ([]string, error) {
+	parts := strings.Split(escapedPath, "/")
+
+	for i, part := range parts {
+		unescaped, err := url.PathUnescape(part)
+		if err != nil {
+			return nil, err
+		}
+		parts[i] = unescaped
+	}
+
+	return parts, nil
+}
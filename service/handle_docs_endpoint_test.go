@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	goGitlab "github.com/xanzy/go-gitlab"
+
+	"github.com/atomicptr/gitlab-composer-integration/composer"
+	"github.com/atomicptr/gitlab-composer-integration/gitlab"
+)
+
+func createDocsTestGeneration() *generation {
+	gen := createTestCatalogueGeneration(time.Now())
+
+	provider, _ := json.Marshal(composer.ProviderRepository{
+		Packages: map[string]composer.PackageInfo{
+			"atomicptr/test": {
+				"dev-master":  {Version: "dev-master", Source: composer.SourceInfo{Reference: "abcd"}},
+				"release/1.0": {Version: "release/1.0", Source: composer.SourceInfo{Reference: "5678"}},
+				"v1.0.0":      {Version: "v1.0.0", Source: composer.SourceInfo{Reference: "1234"}},
+			},
+		},
+	})
+	gen.providers["atomicptr/test"] = provider
+
+	return gen
+}
+
+func TestHandleDocsEndpoint(t *testing.T) {
+	var requests int32
+	server := createPackageFilesTestServer(&requests)
+	defer server.Close()
+
+	s := newTestService(server.URL)
+	assert.Nil(t, s.swapGeneration(createDocsTestGeneration()))
+
+	request := func(path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		s.handleDocsEndpoint(recorder, httptest.NewRequest("GET", path, nil))
+		return recorder
+	}
+
+	recorder := request("/docs/atomicptr/test/v1.0.0/README")
+	assert.EqualValues(t, http.StatusOK, recorder.Code)
+	assert.True(t, strings.HasPrefix(recorder.Body.String(), "# Test Package at 1234\n"))
+	assert.EqualValues(t, "text/markdown; charset=utf-8", recorder.Header().Get("Content-Type"))
+	assert.EqualValues(t, `"1234-readme"`, recorder.Header().Get("ETag"))
+
+	// the file is cached for the ref
+	recorder = request("/docs/atomicptr/test/v1.0.0/readme")
+	assert.True(t, strings.HasPrefix(recorder.Body.String(), "# Test Package at 1234\n"))
+	assert.EqualValues(t, 1, atomic.LoadInt32(&requests))
+
+	recorder = request("/docs/atomicptr/test/dev-master/README")
+	assert.True(t, strings.HasPrefix(recorder.Body.String(), "# Test Package at abcd\n"))
+	assert.EqualValues(t, 2, atomic.LoadInt32(&requests))
+
+	// versions containing a slash are escaped
+	recorder = request("/docs/atomicptr/test/release%2F1.0/README")
+	assert.EqualValues(t, http.StatusOK, recorder.Code)
+	assert.True(t, strings.HasPrefix(recorder.Body.String(), "# Test Package at 5678\n"))
+
+	recorder = request("/docs/atomicptr/test/v1.0.0/LICENSE")
+	assert.EqualValues(t, http.StatusOK, recorder.Code)
+	assert.EqualValues(t, "MIT License", recorder.Body.String())
+	assert.EqualValues(t, "text/plain; charset=utf-8", recorder.Header().Get("Content-Type"))
+
+	assert.EqualValues(t, http.StatusNotFound, request("/docs/atomicptr/test/v1.0.0/CHANGELOG").Code)
+	assert.EqualValues(t, http.StatusNotFound, request("/docs/atomicptr/test/v2.0.0/README").Code)
+	assert.EqualValues(t, http.StatusNotFound, request("/docs/atomicptr/unknown/v1.0.0/README").Code)
+	assert.EqualValues(t, http.StatusNotFound, request("/docs/atomicptr/test/v1.0.0/composer.json").Code)
+	assert.EqualValues(t, http.StatusNotFound, request("/docs/atomicptr/test/README").Code)
+
+	conditional := httptest.NewRequest("GET", "/docs/atomicptr/test/v1.0.0/README", nil)
+	conditional.Header.Set("If-None-Match", `"1234-readme"`)
+	recorder = httptest.NewRecorder()
+	s.handleDocsEndpoint(recorder, conditional)
+	assert.EqualValues(t, http.StatusNotModified, recorder.Code)
+}
+
+func TestPackageFilesAreSharedWithTheWebUi(t *testing.T) {
+	var requests int32
+	server := createPackageFilesTestServer(&requests)
+	defer server.Close()
+
+	s := newTestService(server.URL)
+
+	readme, found, err := s.readme(context.Background(), 42, "1234")
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Contains(t, string(readme), "<h1>Test Package at 1234</h1>")
+
+	file, err := s.packageFile(context.Background(), 42, "1234", "readme")
+	assert.Nil(t, err)
+	assert.True(t, file.Found)
+	assert.EqualValues(t, 1, atomic.LoadInt32(&requests))
+
+	// the files are kept when the generation changes
+	assert.Nil(t, s.swapGeneration(createDocsTestGeneration()))
+	_, err = s.packageFile(context.Background(), 42, "1234", "readme")
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1, atomic.LoadInt32(&requests))
+}
+
+func TestPackageFilesOfOldRefsAreRemoved(t *testing.T) {
+	var requests int32
+	server := createPackageFilesTestServer(&requests)
+	defer server.Close()
+
+	s := newTestService(server.URL)
+
+	for i := 0; i <= maxCachedFileRefs; i++ {
+		_, err := s.packageFile(context.Background(), 42, fmt.Sprintf("ref-%d", i), "readme")
+		assert.Nil(t, err)
+	}
+
+	_, found := s.getFromCache(getPackageFileCacheIdentifier(42, "ref-0", "readme"))
+	assert.False(t, found)
+	_, found = s.getFromCache(getPackageFileCacheIdentifier(42, "ref-1", "readme"))
+	assert.True(t, found)
+
+	// the files of deleted projects are removed
+	s.removePackageFiles(42)
+	_, found = s.getFromCache(getPackageFileCacheIdentifier(42, "ref-1", "readme"))
+	assert.False(t, found)
+}
+
+func TestHandleDocsEndpointMissingFile(t *testing.T) {
+	var requests int32
+	server := createPackageFilesTestServer(&requests)
+	defer server.Close()
+
+	s := newTestService(server.URL)
+	gen := createDocsTestGeneration()
+	gen.catalogue["atomicptr/test"].ProjectId = 43
+	assert.Nil(t, s.swapGeneration(gen))
+
+	recorder := httptest.NewRecorder()
+	s.handleDocsEndpoint(recorder, httptest.NewRequest("GET", "/docs/atomicptr/test/v1.0.0/LICENSE", nil))
+	assert.EqualValues(t, http.StatusNotFound, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "has no LICENSE")
+}
+
+func TestCreateSupportLinks(t *testing.T) {
+	project := &gitlab.ComposerProject{
+		Name:    "atomicptr/test",
+		Project: &goGitlab.Project{},
+	}
+
+	assert.Nil(t, createSupportLinks(project, "v1.0.0", "v1.0.0", ""))
+
+	project.Project.WebURL = "https://gitlab.com/atomicptr/test"
+	project.Project.IssuesEnabled = true
+
+	assert.EqualValues(t, map[string]string{
+		"source": "https://gitlab.com/atomicptr/test/-/tree/feature%2Ftest",
+		"issues": "https://gitlab.com/atomicptr/test/-/issues",
+		"docs":   "https://composer.example.com/docs/atomicptr/test/dev-master/README",
+	}, createSupportLinks(project, "feature/test", "dev-master", "https://composer.example.com/"))
+}
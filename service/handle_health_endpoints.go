@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// the minimum time between two reachability checks of Gitlab triggered by probes
+const gitlabCheckInterval = 30 * time.Second
+
+// the maximum time a reachability check of Gitlab may take
+const gitlabCheckTimeout = 10 * time.Second
+
+type gitlabStatus struct {
+	mutex     sync.Mutex
+	checking  bool
+	reachable bool
+	checkedAt time.Time
+}
+
+// gitlabReadiness only tells if Gitlab is reachable, the endpoint is unauthenticated so the error (which contains
+// the Gitlab url) is only logged
+type gitlabReadiness struct {
+	Reachable bool       `json:"reachable"`
+	CheckedAt *time.Time `json:"checkedAt,omitempty"`
+}
+
+type cacheReadiness struct {
+	LastRefresh *time.Time `json:"lastRefresh,omitempty"`
+	AgeSeconds  float64    `json:"ageSeconds"`
+	Stale       bool       `json:"stale"`
+}
+
+type readiness struct {
+	Ready  bool            `json:"ready"`
+	Leader bool            `json:"leader"`
+	Gitlab gitlabReadiness `json:"gitlab"`
+	Cache  cacheReadiness  `json:"cache"`
+	// DegradedPackages is the amount of packages published with their last known good version, the endpoint is
+	// unauthenticated so the packages are only listed in the scan report
+	DegradedPackages int `json:"degradedPackages"`
+}
+
+func (s *Service) handleHealthzEndpoint(writer http.ResponseWriter, _ *http.Request) {
+	writer.Header().Set("Content-Type", "text/plain")
+	writer.WriteHeader(http.StatusOK)
+
+	_, err := writer.Write([]byte("OK"))
+	if err != nil {
+		s.logger.Println(err)
+	}
+}
+
+func (s *Service) handleReadyzEndpoint(writer http.ResponseWriter, _ *http.Request) {
+	status := readiness{
+		Ready:  s.isIndexReady(),
+		Leader: s.isLeaderInstance(),
+		Gitlab: s.checkGitlabReachability(),
+		Cache: cacheReadiness{
+			Stale: s.isStale(),
+		},
+		DegradedPackages: s.getDegradedPackageCount(),
+	}
+
+	if lastRefresh := s.getLastRefresh(); !lastRefresh.IsZero() {
+		status.Cache.LastRefresh = &lastRefresh
+		status.Cache.AgeSeconds = time.Since(lastRefresh).Seconds()
+	}
+
+	data, err := json.Marshal(status)
+	if err != nil {
+		s.logger.Println(err)
+		http.Error(writer, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+
+	if status.Ready {
+		writer.WriteHeader(http.StatusOK)
+	} else {
+		writer.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	_, err = writer.Write(data)
+	if err != nil {
+		s.logger.Println(err)
+	}
+}
+
+// checkGitlabReachability returns the last known reachability of Gitlab and starts a new check in the
+// background if the last one is outdated, this way probes never have to wait for Gitlab.
+func (s *Service) checkGitlabReachability() gitlabReadiness {
+	s.gitlabStatus.mutex.Lock()
+	defer s.gitlabStatus.mutex.Unlock()
+
+	if !s.gitlabStatus.checking && time.Since(s.gitlabStatus.checkedAt) > gitlabCheckInterval {
+		s.gitlabStatus.checking = true
+		go s.updateGitlabReachability()
+	}
+
+	result := gitlabReadiness{
+		Reachable: s.gitlabStatus.reachable,
+	}
+
+	if !s.gitlabStatus.checkedAt.IsZero() {
+		checkedAt := s.gitlabStatus.checkedAt
+		result.CheckedAt = &checkedAt
+	}
+
+	return result
+}
+
+func (s *Service) updateGitlabReachability() {
+	ctx, cancel := context.WithTimeout(s.ctx, gitlabCheckTimeout)
+	defer cancel()
+
+	err := s.gitlabClient.Validate(ctx)
+	if err != nil {
+		s.logger.Println(errors.Wrap(err, "gitlab is not reachable"))
+	}
+
+	s.gitlabStatus.mutex.Lock()
+	defer s.gitlabStatus.mutex.Unlock()
+
+	s.gitlabStatus.checking = false
+	s.gitlabStatus.checkedAt = time.Now()
+	s.gitlabStatus.reachable = err == nil
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestService(gitlabUrl string) *Service {
+	return New(
+		Config{
+			GitlabUrl:           gitlabUrl,
+			CacheExpireDuration: time.Hour,
+			CacheFilePath:       path.Join(testDir, "cache"),
+			StatsFilePath:       path.Join(testDir, "stats.db"),
+		},
+		log.New(ioutil.Discard, "", 0),
+		make(chan error, 1),
+	)
+}
+
+func TestHandleHealthzEndpoint(t *testing.T) {
+	s := newTestService("https://gitlab.com")
+
+	recorder := httptest.NewRecorder()
+	s.handleHealthzEndpoint(recorder, httptest.NewRequest("GET", "/healthz", nil))
+
+	assert.EqualValues(t, http.StatusOK, recorder.Code)
+}
+
+func TestHandleReadyzEndpointNotReady(t *testing.T) {
+	s := newTestService("https://gitlab.com")
+	s.gitlabStatus.checking = true // don't talk to Gitlab in this test
+
+	recorder := httptest.NewRecorder()
+	s.handleReadyzEndpoint(recorder, httptest.NewRequest("GET", "/readyz", nil))
+
+	assert.EqualValues(t, http.StatusServiceUnavailable, recorder.Code)
+
+	var status readiness
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &status))
+	assert.False(t, status.Ready)
+	assert.Nil(t, status.Cache.LastRefresh)
+}
+
+func TestHandleReadyzEndpoint(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		_, _ = fmt.Fprint(writer, "[]")
+	}))
+	defer server.Close()
+
+	s := newTestService(server.URL)
+	s.setLastRefresh(time.Now().Add(-time.Minute))
+	s.markIndexReady()
+
+	gen := createTestGeneration(time.Now(), "hash")
+	gen.degraded = []*degradedPackage{{Name: "atomicptr/test", ProjectId: 1, Error: "invalid composer.json"}}
+	assert.Nil(t, s.swapGeneration(gen))
+	s.updatePublishMetrics(gen)
+
+	// the first probe starts the reachability check in the background
+	s.checkGitlabReachability()
+	assert.Eventually(t, func() bool {
+		return s.checkGitlabReachability().Reachable
+	}, time.Second, 10*time.Millisecond)
+
+	recorder := httptest.NewRecorder()
+	s.handleReadyzEndpoint(recorder, httptest.NewRequest("GET", "/readyz", nil))
+
+	assert.EqualValues(t, http.StatusOK, recorder.Code)
+
+	var status readiness
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &status))
+	assert.True(t, status.Ready)
+	assert.True(t, status.Gitlab.Reachable)
+	assert.True(t, status.Cache.AgeSeconds >= 60)
+	assert.EqualValues(t, 1, status.DegradedPackages)
+	assert.NotContains(t, recorder.Body.String(), "atomicptr/test")
+}
+
+func TestHandleReadyzEndpointHidesGitlabError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		writer.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	s := newTestService(server.URL)
+	s.markIndexReady()
+
+	s.gitlabStatus.checking = true
+	s.updateGitlabReachability()
+
+	recorder := httptest.NewRecorder()
+	s.handleReadyzEndpoint(recorder, httptest.NewRequest("GET", "/readyz", nil))
+
+	var status readiness
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &status))
+	assert.False(t, status.Gitlab.Reachable)
+	assert.NotContains(t, recorder.Body.String(), server.Listener.Addr().String())
+}
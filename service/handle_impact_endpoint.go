@@ -0,0 +1,66 @@
+package service
+
+import (
+	"net/http"
+
+	"github.com/atomicptr/gitlab-composer-integration/composer"
+)
+
+// handleImpactEndpoint lists the impact reports of the most recent releases, with ?package=vendor/name the
+// report of the latest stable version of the package or of the given ?version= (which does not have to be
+// released yet) is created
+func (s *Service) handleImpactEndpoint(writer http.ResponseWriter, request *http.Request) {
+	s.logger.Printf("Request to \"%s\" from %s (%s)\n", request.URL, request.UserAgent(), request.RemoteAddr)
+
+	query := request.URL.Query()
+	packageName := query.Get("package")
+
+	if packageName == "" {
+		reports, _ := s.loadImpactReports()
+		if reports == nil {
+			reports = []*impactReport{}
+		}
+
+		s.respondJson(writer, http.StatusOK, struct {
+			Reports []*impactReport `json:"reports"`
+		}{reports})
+		return
+	}
+
+	catalogue, found := s.currentCatalogue()
+	if !found {
+		respondIndexUnavailable(writer)
+		return
+	}
+
+	var entry *catalogueEntry
+	for _, candidate := range catalogue {
+		if candidate.Name == packageName {
+			entry = candidate
+		}
+	}
+
+	if entry == nil {
+		http.Error(writer, "package "+packageName+" is not published", http.StatusNotFound)
+		return
+	}
+
+	var version composer.Version
+
+	if versionName := query.Get("version"); versionName != "" {
+		parsed, err := composer.ParseVersion(versionName)
+		if err != nil || parsed.IsBranch() {
+			http.Error(writer, "version has to be a numbered version like 2.0.0", http.StatusBadRequest)
+			return
+		}
+		version = parsed
+	} else if version, found = latestStableVersion(entry); !found {
+		http.Error(writer, "package "+packageName+" has no numbered versions", http.StatusNotFound)
+		return
+	}
+
+	report, _ := createImpactReport(catalogue, packageName, version)
+
+	s.setStaleWarning(writer)
+	s.respondJson(writer, http.StatusOK, report)
+}
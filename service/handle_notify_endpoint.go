@@ -5,6 +5,9 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"time"
+
+	"github.com/atomicptr/gitlab-composer-integration/stats"
 )
 
 func (s *Service) handleNotifyEndpoint(writer http.ResponseWriter, request *http.Request) {
@@ -34,9 +37,42 @@ func (s *Service) handleNotifyEndpoint(writer http.ResponseWriter, request *http
 		return
 	}
 
+	// only packages which are published are recorded, clients can't fill the statistics with arbitrary names
+	published := make(map[string]bool)
+	if catalogue, found := s.currentCatalogue(); found {
+		for _, entry := range catalogue {
+			published[entry.Name] = true
+		}
+	}
+
 	packages := ""
+	var downloads []stats.Download
+
 	for _, pkg := range download.Downloads {
 		packages = fmt.Sprintf("%s\n\tPackage: %s, Version: %s", packages, pkg.Name, pkg.Version)
+
+		if pkg.Name == "" {
+			s.logger.Printf("could not record download of version %s without package name", pkg.Version)
+			continue
+		}
+
+		if pkg.Version == "" {
+			s.logger.Printf("could not record download of %s without version", pkg.Name)
+			continue
+		}
+
+		if !published[pkg.Name] {
+			s.logger.Printf("could not record download of unknown package %s", pkg.Name)
+			continue
+		}
+
+		downloads = append(downloads, stats.Download{Name: pkg.Name, Version: pkg.Version})
+	}
+
+	// all downloads are recorded at once, this way the statistics are only written once per notification
+	err = s.stats.RecordAll(downloads, time.Now())
+	if err != nil {
+		s.logger.Printf("could not record %d downloads because %s", len(downloads), err)
 	}
 
 	s.logger.Printf("Download from %s (%s)%s", request.UserAgent(), request.RemoteAddr, packages)
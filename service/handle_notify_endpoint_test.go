@@ -0,0 +1,53 @@
+package service
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleNotifyEndpointRecordsPublishedPackages(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gci-service")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	s := newTestService("https://gitlab.com")
+	s.config.StatsFilePath = path.Join(dir, "stats.db")
+
+	statsStore, err := s.openStats()
+	assert.Nil(t, err)
+	defer statsStore.Close()
+	s.stats = statsStore
+
+	gen := createTestGeneration(time.Now(), "hash")
+	gen.catalogue["atomicptr/test"] = &catalogueEntry{Name: "atomicptr/test", Versions: []string{"v1.0.0"}}
+	assert.Nil(t, s.swapGeneration(gen))
+
+	body := `{"downloads": [
+		{"name": "atomicptr/test", "version": "1.0.0.0"},
+		{"name": "atomicptr/test", "version": ""},
+		{"name": "atomicptr/unknown", "version": "1.0.0.0"}
+	]}`
+
+	recorder := httptest.NewRecorder()
+	s.handleNotifyEndpoint(recorder, httptest.NewRequest("POST", "/notify", strings.NewReader(body)))
+	assert.EqualValues(t, http.StatusOK, recorder.Code)
+
+	// downloads without version are skipped, the others are still recorded
+	packageStats, found, err := s.stats.Package("atomicptr/test", time.Now())
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.EqualValues(t, 1, packageStats.Downloads.Total)
+
+	// unknown packages are not recorded at all
+	_, found, err = s.stats.Package("atomicptr/unknown", time.Now())
+	assert.Nil(t, err)
+	assert.False(t, found)
+}
@@ -1,111 +1,133 @@
 package service
 
 import (
+	"context"
 	"crypto/sha256"
-	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
-	"github.com/patrickmn/go-cache"
 	"github.com/pkg/errors"
 
 	"github.com/atomicptr/gitlab-composer-integration/composer"
 	"github.com/atomicptr/gitlab-composer-integration/gitlab"
 )
 
-var packageCounter int64
+// clients are told to retry after this amount of seconds if there is no index yet
+const indexRetryAfterSeconds = 30
 
 func (s *Service) handlePackagesJsonEndpoint(writer http.ResponseWriter, request *http.Request) {
 	s.logger.Printf("Request to \"%s\" from %s (%s)\n", request.URL, request.UserAgent(), request.RemoteAddr)
 
-	writer.Header().Set("Content-Type", "application/json")
-
-	// busy loop until you can get a cache...
-	for {
-		if content, found := s.cache.Get(indexCacheKey); found {
-			_, err := writer.Write(content.([]byte))
-			if err != nil {
-				s.logger.Println(errors.Wrap(err, "could not read cache"))
-			}
-
+	if !s.waitForIndex(request.Context()) {
+		if request.Context().Err() != nil {
+			s.logger.Printf("client %s disconnected while waiting for the index", request.RemoteAddr)
 			return
 		}
 
-		time.Sleep(5 * time.Second)
+		respondIndexUnavailable(writer)
+		return
 	}
-}
 
-func (s *Service) fetchComposerData() ([]byte, error) {
-	composerJson, err := s.createComposerRepository()
-	if err != nil {
-		return nil, errors.Wrap(err, "could not create composer repo data")
+	generationId, found := s.currentGenerationId()
+	if !found {
+		respondIndexUnavailable(writer)
+		return
 	}
 
-	jsonData, err := composerJson.ToJson()
+	content, found := s.getFromGeneration(generationId, indexCacheKey)
+	if !found {
+		respondIndexUnavailable(writer)
+		return
+	}
+
+	etag, err := createHash(content)
 	if err != nil {
-		return nil, errors.Wrap(err, "could not transform data to json")
+		s.logger.Println(errors.Wrap(err, "could not create sha256 hash"))
+		http.Error(writer, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
 	}
 
-	return jsonData, nil
+	created, _ := s.generationCreated(generationId)
+
+	s.setStaleWarning(writer)
+	s.serveMetadata(writer, request, content, etag, created, indexCacheControl)
 }
 
-func (s *Service) createComposerRepository() (*composer.Repository, error) {
-	projects, err := s.gitlabClient.FindAllComposerProjects()
-	if err != nil {
-		return nil, errors.Wrap(err, "could not fetch gitlab composer projects")
+// waitForIndex waits until the first index is available, returns false if the request was canceled
+// or the configured wait timeout has been reached before that
+func (s *Service) waitForIndex(ctx context.Context) bool {
+	timer := time.NewTimer(s.config.IndexWaitTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-s.indexReady:
+		return true
+	case <-ctx.Done():
+		return false
+	case <-s.ctx.Done():
+		// the service is shutting down
+		return false
+	case <-timer.C:
+		return false
 	}
+}
 
-	packageCounter = 0
+// setStaleWarning marks the response as stale if the last refresh failed
+func (s *Service) setStaleWarning(writer http.ResponseWriter) {
+	if s.isStale() {
+		writer.Header().Set("Warning", `110 - "Response is Stale"`)
+	}
+}
 
-	providers := make(map[string]composer.Provider)
+func respondIndexUnavailable(writer http.ResponseWriter) {
+	writer.Header().Set("Retry-After", strconv.Itoa(indexRetryAfterSeconds))
+	http.Error(writer, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+}
 
-	for _, project := range projects {
-		if s.config.IsVendorAllowed(project.Vendor) {
-			packages := map[string]composer.PackageInfo{}
-			packages[project.Name] = createComposerPackageInfo(project)
+func (s *Service) fetchComposerData(ctx context.Context) (*generation, error) {
+	gen := newGeneration(time.Now())
 
-			packageData := composer.ProviderRepository{
-				Packages: packages,
-			}
+	err := s.createComposerRepository(ctx, gen)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create composer repo data")
+	}
 
-			data, err := json.Marshal(packageData)
-			if err != nil {
-				s.logger.Println(errors.Wrapf(err, "could not cache project: %s", project.Name))
-				continue
-			}
+	jsonData, err := gen.createIndex(s.config.IsAdvisoryDatabaseEnabled())
+	if err != nil {
+		return nil, errors.Wrap(err, "could not transform data to json")
+	}
 
-			hash, err := createHash(data)
-			if err != nil {
-				s.logger.Println(errors.Wrap(err, "could not create sha256 hash"))
-				continue
-			}
+	gen.index = jsonData
+	return gen, nil
+}
 
-			// store package in cache
-			s.cache.Set(
-				getProjectCacheIdentifier(project.Name),
-				data,
-				cache.DefaultExpiration,
-			)
+// createComposerRepository fetches all composer projects and adds their provider data to the given generation
+func (s *Service) createComposerRepository(ctx context.Context, gen *generation) error {
+	scanResult, err := s.gitlabClient.FindAllComposerProjects(ctx, s.updateScanProgress)
+	if err != nil {
+		return errors.Wrap(err, "could not fetch gitlab composer projects")
+	}
 
-			// store hash
-			s.cache.Set(
-				getProjectHashIdentifier(project.Name),
-				hash,
-				cache.DefaultExpiration,
-			)
+	gen.report = newScanReport(gen.created, scanResult.Outcomes)
 
-			providers[project.Name] = composer.Provider{Sha256: hash}
-		}
+	for _, project := range scanResult.Projects {
+		s.publishProject(gen, project)
 	}
 
-	composerRepository := composer.Repository{
-		Packages:     []struct{}{},
-		NotifyBatch:  "/notify",
-		ProvidersUrl: "/p?package=%package%&hash=%hash%",
-		Providers:    providers,
+	// fresh packages win over last known good versions with the same name
+	for _, failure := range scanResult.Failures() {
+		s.publishLastKnownGood(gen, failure)
 	}
-	return &composerRepository, nil
+
+	gen.report.FinishedAt = time.Now()
+
+	s.updatePublishMetrics(gen)
+
+	return nil
 }
 
 func createHash(str []byte) (string, error) {
@@ -125,9 +147,22 @@ func getProjectHashIdentifier(hash string) string {
 	return fmt.Sprintf("hash:%s", hash)
 }
 
-func createComposerPackageInfo(project *gitlab.ComposerProject) composer.PackageInfo {
+// createComposerPackageInfo creates the metadata of all versions of the project, if the base url of the service
+// is known the versions link to their documentation files
+func createComposerPackageInfo(
+	project *gitlab.ComposerProject,
+	uid packageUidFunc,
+	baseUrl string,
+) composer.PackageInfo {
 	packageInfo := make(composer.PackageInfo)
 
+	var abandoned interface{}
+	if isAbandoned, replacement := project.Abandoned(); replacement != "" {
+		abandoned = replacement
+	} else if isAbandoned {
+		abandoned = true
+	}
+
 	// add dev-master as HEAD
 	packageInfo["dev-master"] = composer.VersionInfo{
 		Name: project.Name,
@@ -136,13 +171,22 @@ func createComposerPackageInfo(project *gitlab.ComposerProject) composer.Package
 			Type:      "git",
 			Url:       project.GitUrl(),
 		},
-		Type:    project.Type(),
-		Version: "dev-master",
-		Uid:     nextPackageId(),
+		Type:      project.Type(),
+		Version:   "dev-master",
+		Uid:       uid("dev-master"),
+		Time:      project.Head.CommittedDate,
+		Homepage:  project.Project.WebURL,
+		Support:   createSupportLinks(project, project.Project.DefaultBranch, "dev-master", baseUrl),
+		Abandoned: abandoned,
 	}
 
 	// add all project tags as well
 	for _, tag := range project.Tags {
+		// composer can't install a version without a commit to check out
+		if tag.Commit == nil {
+			continue
+		}
+
 		packageInfo[tag.Name] = composer.VersionInfo{
 			Name: project.Name,
 			Source: composer.SourceInfo{
@@ -150,16 +194,43 @@ func createComposerPackageInfo(project *gitlab.ComposerProject) composer.Package
 				Type:      "git",
 				Url:       project.GitUrl(),
 			},
-			Type:    project.Type(),
-			Version: tag.Name,
-			Uid:     nextPackageId(),
+			Type:      project.Type(),
+			Version:   tag.Name,
+			Uid:       uid(tag.Name),
+			Time:      tag.Commit.CommittedDate,
+			Homepage:  project.Project.WebURL,
+			Support:   createSupportLinks(project, tag.Name, tag.Name, baseUrl),
+			Abandoned: abandoned,
 		}
 	}
 
 	return packageInfo
 }
 
-func nextPackageId() int64 {
-	packageCounter++
-	return packageCounter
+// createSupportLinks links the source of the version and the issues in Gitlab, the documentation files are linked
+// to the docs endpoint of the service because not everyone installing the package has access to Gitlab
+func createSupportLinks(project *gitlab.ComposerProject, ref, version, baseUrl string) map[string]string {
+	support := make(map[string]string)
+
+	if webUrl := strings.TrimSuffix(project.Project.WebURL, "/"); webUrl != "" {
+		if ref != "" {
+			support["source"] = webUrl + "/-/tree/" + url.PathEscape(ref)
+		}
+
+		if project.Project.IssuesEnabled {
+			support["issues"] = webUrl + "/-/issues"
+		}
+	}
+
+	if baseUrl != "" {
+		// composer only knows the docs link, the changelog and the license are served next to the readme
+		support["docs"] = strings.TrimSuffix(baseUrl, "/") + docsUrlPrefix + project.Name + "/" +
+			url.PathEscape(version) + "/README"
+	}
+
+	if len(support) == 0 {
+		return nil
+	}
+
+	return support
 }
@@ -1,7 +1,12 @@
 package service
 
 import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	goGitlab "github.com/xanzy/go-gitlab"
@@ -9,6 +14,59 @@ import (
 	"github.com/atomicptr/gitlab-composer-integration/gitlab"
 )
 
+func TestHandlePackagesJsonEndpointColdStart(t *testing.T) {
+	s := newTestService("https://gitlab.com")
+	s.config.IndexWaitTimeout = 10 * time.Millisecond
+
+	recorder := httptest.NewRecorder()
+	s.handlePackagesJsonEndpoint(recorder, httptest.NewRequest("GET", "/packages.json", nil))
+
+	assert.EqualValues(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.EqualValues(t, "30", recorder.Header().Get("Retry-After"))
+}
+
+func TestHandlePackagesJsonEndpointClientDisconnected(t *testing.T) {
+	s := newTestService("https://gitlab.com")
+	s.config.IndexWaitTimeout = time.Hour
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		recorder := httptest.NewRecorder()
+		s.handlePackagesJsonEndpoint(recorder, httptest.NewRequest("GET", "/packages.json", nil).WithContext(ctx))
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("handler did not return after the client disconnected")
+	}
+}
+
+func TestHandlePackagesJsonEndpoint(t *testing.T) {
+	s := newTestService("https://gitlab.com")
+	s.config.IndexWaitTimeout = time.Hour
+
+	recorder := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		s.handlePackagesJsonEndpoint(recorder, httptest.NewRequest("GET", "/packages.json", nil))
+		close(done)
+	}()
+
+	gen := newGeneration(time.Now())
+	gen.index = []byte(`{"packages": []}`)
+	assert.Nil(t, s.swapGeneration(gen))
+	s.markIndexReady()
+	<-done
+
+	assert.EqualValues(t, http.StatusOK, recorder.Code)
+	assert.EqualValues(t, `{"packages": []}`, recorder.Body.String())
+}
+
 func TestCreateHash(t *testing.T) {
 	values := map[string]string{
 		"":                                      "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
@@ -37,11 +95,15 @@ func TestGetProjectHashIdentifier(t *testing.T) {
 }
 
 func TestCreateComposerPackageInfo(t *testing.T) {
+	committed := time.Date(2020, 4, 1, 12, 0, 0, 0, time.UTC)
 	commit := goGitlab.Commit{
-		ID: "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+		ID:            "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+		CommittedDate: &committed,
 	}
 	gitlabProject := goGitlab.Project{
-		SSHURLToRepo: "ssh://git@gitlab.com:atomicptr/project.git",
+		SSHURLToRepo:  "ssh://git@gitlab.com:atomicptr/project.git",
+		WebURL:        "https://gitlab.com/atomicptr/project",
+		DefaultBranch: "dev-master",
 	}
 	project := gitlab.ComposerProject{
 		Name:    "atomicptr/test-project",
@@ -55,8 +117,10 @@ func TestCreateComposerPackageInfo(t *testing.T) {
 		},
 	}
 
-	packageCounter = 41
-	packageInfo := createComposerPackageInfo(&project)
+	uids := map[string]int64{"dev-master": 42, "v1.0.0": 43}
+	packageInfo := createComposerPackageInfo(&project, func(version string) int64 {
+		return uids[version]
+	}, "")
 
 	assert.NotNil(t, packageInfo["dev-master"])
 	assert.NotNil(t, packageInfo["v1.0.0"])
@@ -70,15 +134,57 @@ func TestCreateComposerPackageInfo(t *testing.T) {
 		assert.EqualValues(t, project.Head.ID, info.Source.Reference)
 		assert.EqualValues(t, project.GitUrl(), info.Source.Url)
 		assert.EqualValues(t, project.Type(), info.Type)
+		assert.True(t, committed.Equal(*info.Time))
+		assert.EqualValues(t, "https://gitlab.com/atomicptr/project", info.Homepage)
+		assert.EqualValues(t, "https://gitlab.com/atomicptr/project/-/tree/"+version, info.Support["source"])
 	}
 }
 
-func TestNextPackageId(t *testing.T) {
-	initialValue := int64(10)
-	packageCounter = initialValue
+func TestCreateComposerPackageInfoTagWithoutCommit(t *testing.T) {
+	commit := goGitlab.Commit{ID: "1234"}
+	project := gitlab.ComposerProject{
+		Name:    "atomicptr/test-project",
+		Head:    &commit,
+		Project: &goGitlab.Project{},
+		Tags:    []*goGitlab.Tag{{Name: "v1.0.0", Commit: &commit}, {Name: "v2.0.0"}},
+	}
+
+	packageInfo := createComposerPackageInfo(&project, func(version string) int64 {
+		return 0
+	}, "")
+
+	assert.Contains(t, packageInfo, "v1.0.0")
+	assert.NotContains(t, packageInfo, "v2.0.0")
+}
+
+func TestCreateComposerPackageInfoAbandoned(t *testing.T) {
+	commit := goGitlab.Commit{ID: "1234"}
+	project := gitlab.ComposerProject{
+		Name:    "atomicptr/test-project",
+		Head:    &commit,
+		Project: &goGitlab.Project{Archived: true},
+		Tags:    []*goGitlab.Tag{{Name: "v1.0.0", Commit: &commit}},
+	}
+
+	uid := func(version string) int64 {
+		return 0
+	}
+
+	packageInfo := createComposerPackageInfo(&project, uid, "")
+	assert.EqualValues(t, true, packageInfo["dev-master"].Abandoned)
+	assert.EqualValues(t, true, packageInfo["v1.0.0"].Abandoned)
+
+	project.ComposerJson = map[string]interface{}{"abandoned": "atomicptr/replacement"}
+	packageInfo = createComposerPackageInfo(&project, uid, "")
+	assert.EqualValues(t, "atomicptr/replacement", packageInfo["v1.0.0"].Abandoned)
 
-	next := nextPackageId()
+	data, err := json.Marshal(packageInfo["v1.0.0"])
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), `"abandoned":"atomicptr/replacement"`)
 
-	assert.NotEqual(t, initialValue, next)
-	assert.NotEqual(t, next, nextPackageId())
+	project.Project.Archived = false
+	project.ComposerJson = nil
+	data, err = json.Marshal(createComposerPackageInfo(&project, uid, "")["v1.0.0"])
+	assert.Nil(t, err)
+	assert.NotContains(t, string(data), "abandoned")
 }
@@ -7,29 +7,47 @@ import (
 func (s *Service) handleProviderEndpoint(writer http.ResponseWriter, request *http.Request) {
 	s.logger.Printf("Request to \"%s\" from %s (%s)\n", request.URL, request.UserAgent(), request.RemoteAddr)
 
-	writer.Header().Set("Content-Type", "application/json")
-
 	query := request.URL.Query()
 
 	packageName := query.Get("package")
 	hash := query.Get("hash")
 
-	hashData, ok := s.cache.Get(getProjectHashIdentifier(packageName))
-	if !ok || hash != hashData {
+	generationId, ok := s.findProviderGeneration(packageName, hash)
+	s.metrics.observeCacheLookup("hash", ok)
+	if !ok {
 		s.logger.Printf("could not find package %s (hash: %s)\n", packageName, hash)
 		http.Error(writer, http.StatusText(http.StatusNotFound), http.StatusNotFound)
 		return
 	}
 
-	data, ok := s.cache.Get(getProjectCacheIdentifier(packageName))
+	data, ok := s.getFromGeneration(generationId, getProjectCacheIdentifier(packageName))
+	s.metrics.observeCacheLookup("project", ok)
 	if !ok {
 		s.logger.Printf("could not find package %s (hash %s)\n", packageName, hash)
 		http.Error(writer, http.StatusText(http.StatusNotFound), http.StatusNotFound)
 		return
 	}
 
-	_, err := writer.Write(data.([]byte))
-	if err != nil {
-		s.logger.Println(err)
+	created, _ := s.generationCreated(generationId)
+
+	s.setStaleWarning(writer)
+	s.serveMetadata(writer, request, data, hash, created, providerCacheControl)
+}
+
+// findProviderGeneration returns the generation containing the package with the given hash, clients which
+// still use the index of the previous generation will be served from it
+func (s *Service) findProviderGeneration(packageName, hash string) (string, bool) {
+	for _, getId := range []func() (string, bool){s.currentGenerationId, s.previousGenerationId} {
+		generationId, found := getId()
+		if !found {
+			continue
+		}
+
+		hashData, found := s.getFromGeneration(generationId, getProjectHashIdentifier(packageName))
+		if found && hash == string(hashData) {
+			return generationId, true
+		}
 	}
+
+	return "", false
 }
@@ -0,0 +1,40 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+func (s *Service) handleScanReportEndpoint(writer http.ResponseWriter, request *http.Request) {
+	s.logger.Printf("Request to \"%s\" from %s (%s)\n", request.URL, request.UserAgent(), request.RemoteAddr)
+
+	report, found := s.loadScanReport()
+	if !found {
+		http.Error(writer, "no scan has finished yet", http.StatusNotFound)
+		return
+	}
+
+	if status := request.URL.Query().Get("status"); status != "" {
+		report = report.filter(status)
+	}
+
+	// the packages which are published with their last known good version instead
+	data, err := json.Marshal(struct {
+		*scanReport
+		Degraded []*degradedPackage `json:"degraded,omitempty"`
+	}{report, s.currentDegradedPackages()})
+	if err != nil {
+		s.logger.Println(errors.Wrap(err, "could not transform scan report to json"))
+		http.Error(writer, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+
+	_, err = writer.Write(data)
+	if err != nil {
+		s.logger.Println(err)
+	}
+}
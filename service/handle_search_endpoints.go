@@ -0,0 +1,91 @@
+package service
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/atomicptr/gitlab-composer-integration/composer"
+)
+
+// handleSearchEndpoint searches the packages by name, description, keywords and type, used by composer search
+func (s *Service) handleSearchEndpoint(writer http.ResponseWriter, request *http.Request) {
+	s.logger.Printf("Request to \"%s\" from %s (%s)\n", request.URL, request.UserAgent(), request.RemoteAddr)
+
+	entries, found := s.searchEntries()
+	if !found {
+		respondIndexUnavailable(writer)
+		return
+	}
+
+	query := request.URL.Query()
+	matches := search(entries, query.Get("q"), query.Get("type"))
+
+	baseUrl := s.getBaseUrl(request)
+
+	results := composer.SearchResults{
+		Results: make([]composer.SearchResult, len(matches)),
+		Total:   len(matches),
+	}
+
+	for i, entry := range matches {
+		results.Results[i] = composer.SearchResult{
+			Name:        entry.Name,
+			Description: entry.Description,
+			Url:         baseUrl + "/package/" + entry.Name,
+			Repository:  entry.WebUrl,
+			Downloads:   entry.downloads,
+		}
+
+		if entry.Replacement != "" {
+			results.Results[i].Abandoned = entry.Replacement
+		} else if entry.Abandoned {
+			results.Results[i].Abandoned = true
+		}
+	}
+
+	s.setStaleWarning(writer)
+	s.respondJson(writer, http.StatusOK, results)
+}
+
+// handlePackageListEndpoint lists the names of all packages, optionally filtered by vendor, type or a name
+// pattern, used by composer show --all
+func (s *Service) handlePackageListEndpoint(writer http.ResponseWriter, request *http.Request) {
+	s.logger.Printf("Request to \"%s\" from %s (%s)\n", request.URL, request.UserAgent(), request.RemoteAddr)
+
+	entries, found := s.searchEntries()
+	if !found {
+		respondIndexUnavailable(writer)
+		return
+	}
+
+	query := request.URL.Query()
+	vendor := query.Get("vendor")
+	packageType := query.Get("type")
+
+	filter := createNameFilter("*")
+	if pattern := query.Get("filter"); pattern != "" {
+		filter = createNameFilter(pattern)
+	}
+
+	list := composer.PackageList{PackageNames: []string{}}
+
+	for _, entry := range entries {
+		if vendor != "" && !strings.HasPrefix(entry.Name, vendor+"/") {
+			continue
+		}
+
+		if packageType != "" && entry.Type != packageType {
+			continue
+		}
+
+		if filter.MatchString(entry.Name) {
+			list.PackageNames = append(list.PackageNames, entry.Name)
+		}
+	}
+
+	sort.Strings(list.PackageNames)
+
+	s.setStaleWarning(writer)
+	s.respondJson(writer, http.StatusOK, list)
+}
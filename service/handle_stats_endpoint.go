@@ -0,0 +1,82 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/atomicptr/gitlab-composer-integration/stats"
+)
+
+func (s *Service) handleStatsEndpoint(writer http.ResponseWriter, request *http.Request) {
+	s.logger.Printf("Request to \"%s\" from %s (%s)\n", request.URL, request.UserAgent(), request.RemoteAddr)
+
+	packageName := request.URL.Query().Get("package")
+
+	var response interface{}
+
+	if packageName == "" {
+		all, err := s.stats.All(time.Now())
+		if err != nil {
+			s.logger.Println(errors.Wrap(err, "could not read download statistics"))
+			http.Error(writer, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		response = struct {
+			Packages interface{} `json:"packages"`
+		}{all}
+	} else {
+		packageStats, found, err := s.stats.Package(packageName, time.Now())
+		if err != nil {
+			s.logger.Println(errors.Wrapf(err, "could not read download statistics of %s", packageName))
+			http.Error(writer, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		if !found {
+			http.Error(writer, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+			return
+		}
+
+		response = packageStats
+	}
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		s.logger.Println(errors.Wrap(err, "could not transform download statistics to json"))
+		http.Error(writer, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+
+	_, err = writer.Write(data)
+	if err != nil {
+		s.logger.Println(err)
+	}
+}
+
+// openStats opens the download statistics, they are shared through Redis if the cache is and kept in a file
+// used by this instance only otherwise
+func (s *Service) openStats() (stats.Store, error) {
+	if s.config.CacheBackend == CacheBackendRedis {
+		return stats.OpenRedis(s.config.RedisUrl), nil
+	}
+
+	return stats.Open(s.getStatsFilePath())
+}
+
+func (s *Service) getStatsFilePath() string {
+	statsPath := s.config.StatsFilePath
+
+	if statsPath == "" {
+		statsPath = path.Join(os.TempDir(), "gitlab-composer-integration-stats.db")
+	}
+
+	return statsPath
+}
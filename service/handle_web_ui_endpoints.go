@@ -0,0 +1,272 @@
+package service
+
+import (
+	"context"
+	"html/template"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// the maximum time loading the readme of a package from Gitlab may take
+const readmeTimeout = 10 * time.Second
+
+var webTemplateFuncs = template.FuncMap{
+	"formatTime": func(t time.Time) string {
+		return t.UTC().Format("2006-01-02 15:04 MST")
+	},
+	"latestVersion": latestVersion,
+}
+
+var (
+	webIndexPage = template.Must(
+		template.New("index").Funcs(webTemplateFuncs).Parse(webLayoutTemplate + webIndexTemplate),
+	)
+	webPackagePage = template.Must(
+		template.New("package").Funcs(webTemplateFuncs).Parse(webLayoutTemplate + webPackageTemplate),
+	)
+)
+
+type vendorGroup struct {
+	Name     string
+	Packages []*catalogueEntry
+}
+
+type indexPage struct {
+	Stale        bool
+	Updated      time.Time
+	PackageCount int
+	Vendors      []*vendorGroup
+}
+
+type versionRow struct {
+	Version  string
+	Released time.Time
+}
+
+type linkRow struct {
+	Name       string
+	Constraint string
+	// Internal is true if the package is published by this repository
+	Internal bool
+}
+
+type packagePage struct {
+	Stale bool
+	// Published is false if the package is only known from the scan report, e.g. because its composer.json is invalid
+	Published      bool
+	Package        *catalogueEntry
+	Scan           *projectReport
+	Versions       []*versionRow
+	Require        []*linkRow
+	RequireDev     []*linkRow
+	Readme         template.HTML
+	ReadmeError    bool
+	RepositoryName string
+	RepositoryUrl  string
+}
+
+// handleWebIndexEndpoint lists all packages grouped by vendor
+func (s *Service) handleWebIndexEndpoint(writer http.ResponseWriter, request *http.Request) {
+	if request.URL.Path != "/" {
+		http.NotFound(writer, request)
+		return
+	}
+
+	catalogue, found := s.currentCatalogue()
+	if !found {
+		respondIndexUnavailable(writer)
+		return
+	}
+
+	page := indexPage{
+		Stale:        s.isStale(),
+		Updated:      s.getLastRefresh(),
+		PackageCount: len(catalogue),
+	}
+
+	vendors := make(map[string]*vendorGroup)
+
+	for _, entry := range catalogue {
+		vendorName := strings.SplitN(entry.Name, "/", 2)[0]
+
+		vendor, ok := vendors[vendorName]
+		if !ok {
+			vendor = &vendorGroup{Name: vendorName}
+			vendors[vendorName] = vendor
+			page.Vendors = append(page.Vendors, vendor)
+		}
+
+		vendor.Packages = append(vendor.Packages, entry)
+	}
+
+	sort.Slice(page.Vendors, func(i, j int) bool {
+		return page.Vendors[i].Name < page.Vendors[j].Name
+	})
+
+	s.renderPage(writer, webIndexPage, page)
+}
+
+// handlePackagePageEndpoint shows the details of a single package and the outcome of its last scan, packages
+// which could not be published only show the outcome of the scan
+func (s *Service) handlePackagePageEndpoint(writer http.ResponseWriter, request *http.Request) {
+	packageName := strings.TrimPrefix(request.URL.Path, "/package/")
+
+	catalogue, found := s.currentCatalogue()
+	if !found {
+		respondIndexUnavailable(writer)
+		return
+	}
+
+	published := make(map[string]*catalogueEntry)
+	for _, entry := range catalogue {
+		published[entry.Name] = entry
+	}
+
+	report, hasReport := s.loadScanReport()
+
+	entry, found := published[packageName]
+	if !found {
+		if hasReport {
+			if scan, found := report.findPackage(packageName); found {
+				s.renderPage(writer, webPackagePage, packagePage{
+					Stale:   s.isStale(),
+					Package: &catalogueEntry{Name: packageName, ProjectId: scan.ProjectId, Project: scan.Project},
+					Scan:    scan.public(),
+				})
+				return
+			}
+		}
+
+		http.NotFound(writer, request)
+		return
+	}
+
+	baseUrl := s.getBaseUrl(request)
+
+	page := packagePage{
+		Stale:          s.isStale(),
+		Published:      true,
+		Package:        entry,
+		Versions:       createVersionRows(entry),
+		Require:        createLinkRows(entry.Require, published),
+		RequireDev:     createLinkRows(entry.RequireDev, published),
+		RepositoryName: getRepositoryName(baseUrl),
+		RepositoryUrl:  baseUrl,
+	}
+
+	if hasReport {
+		if scan, found := report.findProject(entry.ProjectId); found {
+			page.Scan = scan.public()
+		}
+	}
+
+	if entry.Reference != "" {
+		ctx, cancel := context.WithTimeout(request.Context(), readmeTimeout)
+		defer cancel()
+
+		readme, _, err := s.readme(ctx, entry.ProjectId, entry.Reference)
+		if err != nil {
+			s.logger.Println(errors.Wrapf(err, "could not load readme of %s", entry.Name))
+			page.ReadmeError = true
+		}
+		page.Readme = readme
+	}
+
+	s.renderPage(writer, webPackagePage, page)
+}
+
+func (s *Service) renderPage(writer http.ResponseWriter, page *template.Template, data interface{}) {
+	writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	if err := page.ExecuteTemplate(writer, "layout", data); err != nil {
+		s.logger.Println(errors.Wrapf(err, "could not render page %s", page.Name()))
+	}
+}
+
+// latestVersion returns the most recently released version which is not a development version
+func latestVersion(entry *catalogueEntry) string {
+	latest := ""
+	var latestReleased time.Time
+
+	for _, version := range entry.Versions {
+		if strings.HasPrefix(version, "dev-") {
+			continue
+		}
+
+		released := entry.Released[version]
+		if latest == "" || released.After(latestReleased) {
+			latest = version
+			latestReleased = released
+		}
+	}
+
+	if latest == "" && len(entry.Versions) > 0 {
+		return entry.Versions[0]
+	}
+
+	return latest
+}
+
+// createVersionRows returns the versions of the package, the most recent first
+func createVersionRows(entry *catalogueEntry) []*versionRow {
+	rows := make([]*versionRow, 0, len(entry.Versions))
+	for _, version := range entry.Versions {
+		rows = append(rows, &versionRow{Version: version, Released: entry.Released[version]})
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].Released.After(rows[j].Released)
+	})
+
+	return rows
+}
+
+func createLinkRows(links map[string]string, published map[string]*catalogueEntry) []*linkRow {
+	rows := make([]*linkRow, 0, len(links))
+	for name, constraint := range links {
+		_, internal := published[name]
+		rows = append(rows, &linkRow{Name: name, Constraint: constraint, Internal: internal})
+	}
+
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i].Name < rows[j].Name
+	})
+
+	return rows
+}
+
+// getBaseUrl returns the configured url of the service, if there is none the url the client used to reach the
+// service is taken from the headers of the request
+func (s *Service) getBaseUrl(request *http.Request) string {
+	if s.config.BaseUrl != "" {
+		return strings.TrimSuffix(s.config.BaseUrl, "/")
+	}
+
+	return getRequestBaseUrl(request)
+}
+
+// getRepositoryName returns the host name of the service, which is used as name of the repository in the
+// composer.json of the users
+func getRepositoryName(baseUrl string) string {
+	parsed, err := url.Parse(baseUrl)
+	if err != nil {
+		return ""
+	}
+
+	return parsed.Hostname()
+}
+
+// getRequestBaseUrl returns the url the client used to reach the service
+func getRequestBaseUrl(request *http.Request) string {
+	scheme := "http"
+	if request.TLS != nil || request.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+
+	return scheme + "://" + request.Host
+}
@@ -0,0 +1,230 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/atomicptr/gitlab-composer-integration/composer"
+)
+
+// createPackageFilesTestServer serves the readme and the license of project 42 like Gitlab, the requests of the
+// readme are counted
+func createPackageFilesTestServer(requests *int32) *httptest.Server {
+	mux := http.NewServeMux()
+
+	readme := func(writer http.ResponseWriter, request *http.Request) {
+		atomic.AddInt32(requests, 1)
+		_, _ = fmt.Fprintf(writer, "# Test Package at %s\n\n<script>alert(1)</script>", request.URL.Query().Get("ref"))
+	}
+	license := func(writer http.ResponseWriter, _ *http.Request) {
+		_, _ = fmt.Fprint(writer, "MIT License")
+	}
+
+	mux.HandleFunc("/api/v4/projects/42/repository/files/README.md/raw", readme)
+	mux.HandleFunc("/api/v4/projects/42/repository/files/LICENSE/raw", license)
+
+	return httptest.NewServer(mux)
+}
+
+func createTestWebUiGeneration() *generation {
+	gen := createTestCatalogueGeneration(time.Now())
+
+	entry := gen.catalogue["atomicptr/test"]
+	entry.Description = "A package for testing"
+	entry.License = []string{"MIT"}
+	entry.Reference = "1234"
+	entry.Versions = []string{"dev-master", "v1.0.0", "v1.1.0"}
+	entry.Released = map[string]time.Time{
+		"dev-master": time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC),
+		"v1.0.0":     time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		"v1.1.0":     time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC),
+	}
+	entry.Require = map[string]string{"php": ">=7.2", "atomicptr/other": "^1.0"}
+	entry.RequireDev = map[string]string{"phpunit/phpunit": "^8.0"}
+
+	gen.catalogue["atomicptr/other"] = &catalogueEntry{
+		Name:        "atomicptr/other",
+		ProjectId:   43,
+		Project:     "atomicptr/other",
+		Versions:    []string{"dev-master"},
+		Abandoned:   true,
+		Replacement: "atomicptr/test",
+	}
+
+	return gen
+}
+
+func TestHandleWebIndexEndpoint(t *testing.T) {
+	s := newTestService("https://gitlab.com")
+
+	recorder := httptest.NewRecorder()
+	s.handleWebIndexEndpoint(recorder, httptest.NewRequest("GET", "/", nil))
+	assert.EqualValues(t, http.StatusServiceUnavailable, recorder.Code)
+
+	assert.Nil(t, s.swapGeneration(createTestWebUiGeneration()))
+
+	recorder = httptest.NewRecorder()
+	s.handleWebIndexEndpoint(recorder, httptest.NewRequest("GET", "/", nil))
+	assert.EqualValues(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Header().Get("Content-Type"), "text/html")
+
+	body := recorder.Body.String()
+	assert.Contains(t, body, `<a href="/package/atomicptr/test">atomicptr/test</a>`)
+	assert.Contains(t, body, `<a href="/package/atomicptr/other">atomicptr/other</a>`)
+	assert.Contains(t, body, "A package for testing")
+	assert.Contains(t, body, "v1.1.0")
+
+	recorder = httptest.NewRecorder()
+	s.handleWebIndexEndpoint(recorder, httptest.NewRequest("GET", "/unknown", nil))
+	assert.EqualValues(t, http.StatusNotFound, recorder.Code)
+}
+
+func TestHandlePackagePageEndpoint(t *testing.T) {
+	var requests int32
+	server := createPackageFilesTestServer(&requests)
+	defer server.Close()
+
+	s := newTestService(server.URL)
+	assert.Nil(t, s.swapGeneration(createTestWebUiGeneration()))
+
+	recorder := httptest.NewRecorder()
+	s.handlePackagePageEndpoint(recorder, httptest.NewRequest("GET", "/package/atomicptr/unknown", nil))
+	assert.EqualValues(t, http.StatusNotFound, recorder.Code)
+
+	request := httptest.NewRequest("GET", "/package/atomicptr/test", nil)
+	request.Host = "composer.example.com"
+
+	recorder = httptest.NewRecorder()
+	s.handlePackagePageEndpoint(recorder, request)
+	assert.EqualValues(t, http.StatusOK, recorder.Code)
+
+	body := recorder.Body.String()
+	assert.Contains(t, body, "composer config repositories.composer.example.com composer http://composer.example.com")
+	assert.Contains(t, body, "composer require atomicptr/test")
+	assert.Contains(t, body, "MIT")
+	assert.Contains(t, body, "2020-02-01 00:00 UTC")
+	assert.Contains(t, body, `<a href="/package/atomicptr/other">atomicptr/other</a>`)
+	assert.Contains(t, body, "phpunit/phpunit")
+	assert.Contains(t, body, "<h1>Test Package at 1234</h1>")
+	assert.NotContains(t, body, "<script>alert(1)</script>")
+
+	// the readme is cached for the reference
+	recorder = httptest.NewRecorder()
+	s.handlePackagePageEndpoint(recorder, request)
+	assert.Contains(t, recorder.Body.String(), "<h1>Test Package at 1234</h1>")
+	assert.EqualValues(t, 1, atomic.LoadInt32(&requests))
+}
+
+func TestHandlePackagePageEndpointConfiguredBaseUrl(t *testing.T) {
+	var requests int32
+	server := createPackageFilesTestServer(&requests)
+	defer server.Close()
+
+	s := newTestService(server.URL)
+	s.config.BaseUrl = "https://composer.example.com/"
+
+	gen := createTestWebUiGeneration()
+	delete(gen.catalogue["atomicptr/test"].Released, "dev-master")
+	assert.Nil(t, s.swapGeneration(gen))
+
+	request := httptest.NewRequest("GET", "/package/atomicptr/test", nil)
+	request.Host = "attacker.example.org"
+
+	recorder := httptest.NewRecorder()
+	s.handlePackagePageEndpoint(recorder, request)
+	assert.EqualValues(t, http.StatusOK, recorder.Code)
+
+	body := recorder.Body.String()
+	assert.Contains(t, body, "composer config repositories.composer.example.com composer https://composer.example.com\n")
+	assert.NotContains(t, body, "attacker.example.org")
+
+	// versions without release date show none
+	assert.NotContains(t, body, "0001-01-01")
+}
+
+func TestHandlePackagePageEndpointWithoutReadme(t *testing.T) {
+	var requests int32
+	server := createPackageFilesTestServer(&requests)
+	defer server.Close()
+
+	s := newTestService(server.URL)
+	gen := createTestWebUiGeneration()
+	gen.catalogue["atomicptr/other"].Reference = "5678"
+	assert.Nil(t, s.swapGeneration(gen))
+
+	recorder := httptest.NewRecorder()
+	s.handlePackagePageEndpoint(recorder, httptest.NewRequest("GET", "/package/atomicptr/other", nil))
+	assert.EqualValues(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "This package has no README.")
+	assert.Contains(t, recorder.Body.String(), `This package is abandoned, use <a href="/package/atomicptr/test">`)
+}
+
+func TestHandlePackagePageEndpointShowsScanOutcome(t *testing.T) {
+	var requests int32
+	server := createPackageFilesTestServer(&requests)
+	defer server.Close()
+
+	s := newTestService(server.URL)
+	assert.Nil(t, s.swapGeneration(createTestWebUiGeneration()))
+	s.storeScanReport(&scanReport{
+		Projects: []*projectReport{
+			{ProjectId: 42, Project: "atomicptr/test", Package: "atomicptr/test", Status: scanStatusPublished},
+			{
+				ProjectId:   44,
+				Project:     "atomicptr/broken",
+				Package:     "atomicptr/broken",
+				Status:      "invalid-schema",
+				Error:       "could not validate composer.json of gitlab.internal",
+				Diagnostics: []composer.ValidationError{{Path: "require.php", Message: "Invalid type"}},
+			},
+			{ProjectId: 45, Project: "atomicptr/docs", Status: "skipped-no-composer-json"},
+			{ProjectId: 46, Project: "atomicptr/private", Status: "api-error", Error: "GET https://gitlab.internal: 500"},
+		},
+	})
+
+	recorder := httptest.NewRecorder()
+	s.handlePackagePageEndpoint(recorder, httptest.NewRequest("GET", "/package/atomicptr/test", nil))
+	assert.EqualValues(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `<span class="tag">published</span>`)
+
+	// packages which could not be published show why
+	recorder = httptest.NewRecorder()
+	s.handlePackagePageEndpoint(recorder, httptest.NewRequest("GET", "/package/atomicptr/broken", nil))
+	assert.EqualValues(t, http.StatusOK, recorder.Code)
+
+	body := recorder.Body.String()
+	assert.Contains(t, body, "This package is not published")
+	assert.Contains(t, body, `<span class="tag">invalid-schema</span>`)
+	assert.Contains(t, body, "<code>require.php</code>")
+	assert.NotContains(t, body, "composer require")
+	assert.NotContains(t, body, "gitlab.internal")
+
+	// projects which are no packages are not revealed
+	for _, path := range []string{"/package/atomicptr/docs", "/package/atomicptr/private"} {
+		recorder = httptest.NewRecorder()
+		s.handlePackagePageEndpoint(recorder, httptest.NewRequest("GET", path, nil))
+		assert.EqualValues(t, http.StatusNotFound, recorder.Code)
+	}
+}
+
+func TestLatestVersion(t *testing.T) {
+	entry := createTestWebUiGeneration().catalogue["atomicptr/test"]
+	assert.EqualValues(t, "v1.1.0", latestVersion(entry))
+
+	entry.Versions = []string{"dev-master"}
+	assert.EqualValues(t, "dev-master", latestVersion(entry))
+}
+
+func TestCreateVersionRows(t *testing.T) {
+	rows := createVersionRows(createTestWebUiGeneration().catalogue["atomicptr/test"])
+	assert.Len(t, rows, 3)
+	assert.EqualValues(t, "dev-master", rows[0].Version)
+	assert.EqualValues(t, "v1.1.0", rows[1].Version)
+	assert.EqualValues(t, "v1.0.0", rows[2].Version)
+}
@@ -0,0 +1,275 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/atomicptr/gitlab-composer-integration/composer"
+)
+
+const impactReportsCacheKey = "impact-reports"
+
+// the amount of impact reports which are kept
+const maxImpactReports = 50
+
+// impactReport shows which packages allow a (new) version of a package they depend on
+type impactReport struct {
+	Package   string    `json:"package"`
+	Version   string    `json:"version"`
+	CreatedAt time.Time `json:"createdAt"`
+	// Allowed contains the dependents whose constraint allows the version
+	Allowed []*impactDependent `json:"allowed"`
+	// PinnedBelow contains the dependents which only allow lower versions
+	PinnedBelow []*impactDependent `json:"pinnedBelow"`
+	// Excluded contains the dependents which exclude the version for other reasons, e.g. by requiring a newer one
+	Excluded []*impactDependent `json:"excluded"`
+	// Invalid contains the dependents whose constraint could not be evaluated
+	Invalid []*impactDependent `json:"invalid"`
+}
+
+type impactDependent struct {
+	Name       string `json:"name"`
+	Constraint string `json:"constraint"`
+	Dev        bool   `json:"dev"`
+	Error      string `json:"error,omitempty"`
+}
+
+// createImpactReport evaluates the constraints of all packages requiring the given package against the version,
+// the version does not have to be published yet
+func createImpactReport(
+	catalogue []*catalogueEntry,
+	packageName string,
+	version composer.Version,
+) (*impactReport, bool) {
+	graph := createDependencyGraph(catalogue, true)
+
+	node, found := graph.nodes[packageName]
+	if !found {
+		return nil, false
+	}
+
+	var published []composer.Version
+	for _, entry := range catalogue {
+		if entry.Name == packageName {
+			published = parseVersions(entry.Versions)
+		}
+	}
+
+	report := &impactReport{
+		Package:     packageName,
+		Version:     version.String(),
+		CreatedAt:   time.Now(),
+		Allowed:     []*impactDependent{},
+		PinnedBelow: []*impactDependent{},
+		Excluded:    []*impactDependent{},
+		Invalid:     []*impactDependent{},
+	}
+
+	for _, link := range node.RequiredBy {
+		dependent := &impactDependent{Name: link.Name, Constraint: link.Constraint, Dev: link.Dev}
+
+		constraint, err := composer.ParseConstraint(link.Constraint)
+		switch {
+		case err != nil:
+			dependent.Error = err.Error()
+			report.Invalid = append(report.Invalid, dependent)
+		case constraint.Matches(version):
+			report.Allowed = append(report.Allowed, dependent)
+		case allowsLowerVersion(constraint, published, version):
+			report.PinnedBelow = append(report.PinnedBelow, dependent)
+		default:
+			report.Excluded = append(report.Excluded, dependent)
+		}
+	}
+
+	return report, true
+}
+
+func allowsLowerVersion(constraint *composer.Constraint, versions []composer.Version, version composer.Version) bool {
+	for _, published := range versions {
+		if published.Compare(version) < 0 && constraint.Matches(published) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseVersions returns the numbered versions, branches and invalid versions are left out
+func parseVersions(versions []string) []composer.Version {
+	var parsed []composer.Version
+	for _, version := range versions {
+		if v, err := composer.ParseVersion(version); err == nil && !v.IsBranch() {
+			parsed = append(parsed, v)
+		}
+	}
+	return parsed
+}
+
+// latestStableVersion returns the highest stable version of the package, if there is none the highest version
+func latestStableVersion(entry *catalogueEntry) (composer.Version, bool) {
+	versions := parseVersions(entry.Versions)
+	if len(versions) == 0 {
+		return composer.Version{}, false
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		if versions[i].IsStable() != versions[j].IsStable() {
+			return !versions[i].IsStable()
+		}
+		return versions[i].Compare(versions[j]) < 0
+	})
+
+	return versions[len(versions)-1], true
+}
+
+// findNewReleases returns the versions of all packages which have been added since the previous catalogue,
+// packages which have not been published before are left out
+func findNewReleases(previous, current []*catalogueEntry) map[string][]composer.Version {
+	known := make(map[string]map[string]bool)
+	for _, entry := range previous {
+		known[entry.Name] = make(map[string]bool)
+		for _, version := range entry.Versions {
+			known[entry.Name][version] = true
+		}
+	}
+
+	releases := make(map[string][]composer.Version)
+
+	for _, entry := range current {
+		versions, found := known[entry.Name]
+		if !found {
+			continue
+		}
+
+		for _, version := range parseVersions(entry.Versions) {
+			if !versions[version.String()] {
+				releases[entry.Name] = append(releases[entry.Name], version)
+			}
+		}
+	}
+
+	return releases
+}
+
+// reportImpact creates impact reports for all versions released since the previous catalogue, stores them and
+// posts them to the webhook in the background if configured
+func (s *Service) reportImpact(previous, current []*catalogueEntry) {
+	releases := findNewReleases(previous, current)
+	if len(releases) == 0 {
+		return
+	}
+
+	names := make([]string, 0, len(releases))
+	for name := range releases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var reports []*impactReport
+
+	for _, name := range names {
+		for _, version := range releases[name] {
+			report, found := createImpactReport(current, name, version)
+			if !found {
+				continue
+			}
+
+			s.logger.Printf(
+				"%s %s was released, %d dependents allow it, %d are pinned below",
+				name,
+				version,
+				len(report.Allowed),
+				len(report.PinnedBelow),
+			)
+			reports = append(reports, report)
+		}
+	}
+
+	s.storeImpactReports(reports)
+
+	if s.config.ImpactWebhookUrl == "" {
+		return
+	}
+
+	// a slow webhook must not delay the refresh, shutting down waits for the reports to be sent
+	s.startWorker(func() {
+		for _, report := range reports {
+			if err := s.postImpactReport(context.Background(), report); err != nil {
+				s.logger.Println(errors.Wrapf(err, "could not post impact report of %s %s", report.Package, report.Version))
+			}
+		}
+	})
+}
+
+// storeImpactReports adds the reports to the stored ones, only the most recent reports are kept
+func (s *Service) storeImpactReports(reports []*impactReport) {
+	stored, _ := s.loadImpactReports()
+
+	reports = append(reports, stored...)
+	if len(reports) > maxImpactReports {
+		reports = reports[:maxImpactReports]
+	}
+
+	data, err := json.Marshal(reports)
+	if err == nil {
+		err = s.cache.Set(impactReportsCacheKey, data)
+	}
+
+	if err != nil {
+		s.logger.Println(errors.Wrap(err, "could not store impact reports"))
+	}
+}
+
+// loadImpactReports returns the reports of the most recent releases, the newest first
+func (s *Service) loadImpactReports() ([]*impactReport, bool) {
+	data, found := s.getFromCache(impactReportsCacheKey)
+	if !found {
+		return nil, false
+	}
+
+	var reports []*impactReport
+	if err := json.Unmarshal(data, &reports); err != nil {
+		s.logger.Println(errors.Wrap(err, "could not read impact reports"))
+		return nil, false
+	}
+
+	return reports, true
+}
+
+// postImpactReport sends the report as JSON to the configured webhook
+func (s *Service) postImpactReport(ctx context.Context, report *impactReport) error {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.config.HttpTimeout)
+	defer cancel()
+
+	request, err := http.NewRequest(http.MethodPost, s.config.ImpactWebhookUrl, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "gitlab-composer-integration")
+
+	response, err := http.DefaultClient.Do(request.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with %s", response.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/atomicptr/gitlab-composer-integration/composer"
+)
+
+func createTestImpactCatalogue() []*catalogueEntry {
+	return []*catalogueEntry{
+		{Name: "acme/logger", Versions: []string{"dev-master", "v1.0.0", "v1.1.0", "v2.0.0-beta"}},
+		{Name: "acme/http-client", Require: map[string]string{"acme/logger": "^1.0 || ^2.0"}},
+		{Name: "acme/website", Require: map[string]string{"acme/logger": "~1.0"}},
+		{Name: "acme/legacy", Require: map[string]string{"acme/logger": "^3.0"}},
+		{Name: "acme/testing", RequireDev: map[string]string{"acme/logger": "latest"}},
+		{Name: "acme/unrelated"},
+	}
+}
+
+func impactTestNames(dependents []*impactDependent) []string {
+	names := make([]string, len(dependents))
+	for i, dependent := range dependents {
+		names[i] = dependent.Name
+	}
+	return names
+}
+
+func TestCreateImpactReport(t *testing.T) {
+	version, _ := composer.ParseVersion("2.0.0")
+
+	_, found := createImpactReport(createTestImpactCatalogue(), "acme/unknown", version)
+	assert.False(t, found)
+
+	report, found := createImpactReport(createTestImpactCatalogue(), "acme/logger", version)
+	assert.True(t, found)
+	assert.EqualValues(t, "acme/logger", report.Package)
+	assert.EqualValues(t, "2.0.0", report.Version)
+	assert.EqualValues(t, []string{"acme/http-client"}, impactTestNames(report.Allowed))
+	assert.EqualValues(t, []string{"acme/website"}, impactTestNames(report.PinnedBelow))
+	assert.EqualValues(t, []string{"acme/legacy"}, impactTestNames(report.Excluded))
+	assert.EqualValues(t, []string{"acme/testing"}, impactTestNames(report.Invalid))
+	assert.True(t, report.Invalid[0].Dev)
+	assert.NotEmpty(t, report.Invalid[0].Error)
+}
+
+func TestLatestStableVersion(t *testing.T) {
+	version, found := latestStableVersion(createTestImpactCatalogue()[0])
+	assert.True(t, found)
+	assert.EqualValues(t, "v1.1.0", version.String())
+
+	version, found = latestStableVersion(&catalogueEntry{Versions: []string{"dev-master", "1.0.0-RC1"}})
+	assert.True(t, found)
+	assert.EqualValues(t, "1.0.0-RC1", version.String())
+
+	_, found = latestStableVersion(&catalogueEntry{Versions: []string{"dev-master"}})
+	assert.False(t, found)
+}
+
+func TestFindNewReleases(t *testing.T) {
+	previous := []*catalogueEntry{
+		{Name: "acme/logger", Versions: []string{"dev-master", "v1.0.0"}},
+	}
+	current := []*catalogueEntry{
+		{Name: "acme/logger", Versions: []string{"dev-master", "dev-feature", "v1.0.0", "v1.1.0", "invalid"}},
+		{Name: "acme/new", Versions: []string{"v1.0.0"}},
+	}
+
+	releases := findNewReleases(previous, current)
+	assert.Len(t, releases, 1)
+	assert.Len(t, releases["acme/logger"], 1)
+	assert.EqualValues(t, "v1.1.0", releases["acme/logger"][0].String())
+}
+
+func TestReportImpact(t *testing.T) {
+	received := make(chan impactReport, 2)
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		var report impactReport
+		data, _ := ioutil.ReadAll(request.Body)
+		_ = json.Unmarshal(data, &report)
+		received <- report
+	}))
+	defer server.Close()
+
+	s := newTestService("https://gitlab.com")
+	s.config.ImpactWebhookUrl = server.URL
+	s.config.HttpTimeout = time.Second
+
+	current := createTestImpactCatalogue()
+	previous := createTestImpactCatalogue()
+	previous[0] = &catalogueEntry{Name: "acme/logger", Versions: []string{"dev-master", "v1.0.0"}}
+
+	s.reportImpact(previous, current)
+
+	select {
+	case report := <-received:
+		assert.EqualValues(t, "acme/logger", report.Package)
+		assert.EqualValues(t, "v1.1.0", report.Version)
+	case <-time.After(time.Second):
+		assert.Fail(t, "the webhook did not receive the report")
+	}
+
+	select {
+	case report := <-received:
+		assert.EqualValues(t, "v2.0.0-beta", report.Version)
+	case <-time.After(time.Second):
+		assert.Fail(t, "the webhook did not receive the report")
+	}
+
+	reports, found := s.loadImpactReports()
+	assert.True(t, found)
+	assert.Len(t, reports, 2)
+
+	// nothing changed, there are no new reports
+	s.reportImpact(current, current)
+	reports, _ = s.loadImpactReports()
+	assert.Len(t, reports, 2)
+}
+
+func TestReportImpactDoesNotWaitForWebhook(t *testing.T) {
+	release := make(chan struct{})
+	received := make(chan struct{}, 2)
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		<-release
+		received <- struct{}{}
+	}))
+	defer server.Close()
+
+	s := newTestService("https://gitlab.com")
+	s.config.ImpactWebhookUrl = server.URL
+	s.config.HttpTimeout = 10 * time.Second
+
+	previous := createTestImpactCatalogue()
+	previous[0] = &catalogueEntry{Name: "acme/logger", Versions: []string{"dev-master", "v1.0.0"}}
+
+	done := make(chan struct{})
+	go func() {
+		s.reportImpact(previous, createTestImpactCatalogue())
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		assert.Fail(t, "reporting the impact waited for the webhook")
+	}
+
+	reports, _ := s.loadImpactReports()
+	assert.Len(t, reports, 2)
+
+	// the reports are still sent before the workers are done
+	close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.Nil(t, s.waitForWorkers(ctx))
+	assert.Len(t, received, 2)
+}
+
+func TestStoreImpactReportsKeepsTheMostRecent(t *testing.T) {
+	s := newTestService("https://gitlab.com")
+
+	for i := 0; i < maxImpactReports+5; i++ {
+		s.storeImpactReports([]*impactReport{{Package: "acme/logger", Version: "1.0.0"}})
+	}
+
+	reports, _ := s.loadImpactReports()
+	assert.Len(t, reports, maxImpactReports)
+}
+
+func TestHandleImpactEndpoint(t *testing.T) {
+	s := newTestService("https://gitlab.com")
+
+	recorder := httptest.NewRecorder()
+	s.handleImpactEndpoint(recorder, httptest.NewRequest("GET", "/impact.json", nil))
+	assert.EqualValues(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"reports": []}`, recorder.Body.String())
+
+	recorder = httptest.NewRecorder()
+	s.handleImpactEndpoint(recorder, httptest.NewRequest("GET", "/impact.json?package=acme/logger", nil))
+	assert.EqualValues(t, http.StatusServiceUnavailable, recorder.Code)
+
+	gen := createTestGeneration(time.Now(), "hash")
+	for _, entry := range createTestImpactCatalogue() {
+		gen.catalogue[entry.Name] = entry
+	}
+	assert.Nil(t, s.swapGeneration(gen))
+
+	recorder = httptest.NewRecorder()
+	s.handleImpactEndpoint(recorder, httptest.NewRequest("GET", "/impact.json?package=acme/logger", nil))
+	assert.EqualValues(t, http.StatusOK, recorder.Code)
+
+	var report impactReport
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &report))
+	assert.EqualValues(t, "v1.1.0", report.Version)
+	assert.Len(t, report.Allowed, 2)
+
+	recorder = httptest.NewRecorder()
+	s.handleImpactEndpoint(recorder, httptest.NewRequest("GET", "/impact.json?package=acme/logger&version=3.0", nil))
+	assert.EqualValues(t, http.StatusOK, recorder.Code)
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &report))
+	assert.EqualValues(t, []string{"acme/legacy"}, impactTestNames(report.Allowed))
+
+	recorder = httptest.NewRecorder()
+	s.handleImpactEndpoint(recorder, httptest.NewRequest("GET", "/impact.json?package=acme/logger&version=latest", nil))
+	assert.EqualValues(t, http.StatusBadRequest, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	s.handleImpactEndpoint(recorder, httptest.NewRequest("GET", "/impact.json?package=acme/unrelated", nil))
+	assert.EqualValues(t, http.StatusNotFound, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	s.handleImpactEndpoint(recorder, httptest.NewRequest("GET", "/impact.json?package=acme/unknown", nil))
+	assert.EqualValues(t, http.StatusNotFound, recorder.Code)
+}
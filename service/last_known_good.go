@@ -0,0 +1,99 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/atomicptr/gitlab-composer-integration/composer"
+	"github.com/atomicptr/gitlab-composer-integration/gitlab"
+)
+
+// lastKnownGood is the package data of the last successful scan of a project, it is published instead
+// if a later scan of the project fails
+type lastKnownGood struct {
+	Name     string               `json:"name"`
+	Vendor   string               `json:"vendor"`
+	Packages composer.PackageInfo `json:"packages"`
+	// Catalogue contains the details of the package
+	Catalogue *catalogueEntry `json:"catalogue,omitempty"`
+	Scanned   time.Time       `json:"scanned"`
+}
+
+// degradedPackage is a package which is published with its last known good data
+type degradedPackage struct {
+	Name        string    `json:"name"`
+	ProjectId   int       `json:"projectId"`
+	Project     string    `json:"project"`
+	Error       string    `json:"error"`
+	LastScanned time.Time `json:"lastScanned"`
+}
+
+func getLastKnownGoodCacheIdentifier(projectId int) string {
+	return fmt.Sprintf("lkg:%d", projectId)
+}
+
+// storeLastKnownGood remembers the package data of a successfully scanned project
+func (s *Service) storeLastKnownGood(projectId int, lkg *lastKnownGood) {
+	data, err := json.Marshal(lkg)
+	if err == nil {
+		err = s.cache.Set(getLastKnownGoodCacheIdentifier(projectId), data)
+	}
+
+	if err != nil {
+		s.logger.Println(errors.Wrapf(err, "could not store last known good version of %s", lkg.Name))
+	}
+}
+
+// removeLastKnownGood forgets the package data of a project which does not exist anymore
+func (s *Service) removeLastKnownGood(projectId int) {
+	if err := s.cache.Delete(getLastKnownGoodCacheIdentifier(projectId)); err != nil {
+		s.logger.Println(errors.Wrapf(err, "could not remove last known good version of project %d", projectId))
+	}
+}
+
+// loadLastKnownGood returns the package data of the last successful scan of the project
+func (s *Service) loadLastKnownGood(projectId int) (*lastKnownGood, bool) {
+	data, found := s.getFromCache(getLastKnownGoodCacheIdentifier(projectId))
+	if !found {
+		return nil, false
+	}
+
+	var lkg lastKnownGood
+	if err := json.Unmarshal(data, &lkg); err != nil {
+		s.logger.Println(errors.Wrapf(err, "could not read last known good version of project %d", projectId))
+		return nil, false
+	}
+
+	return &lkg, true
+}
+
+// recoverFailedProject returns the last known good data of a project which could not be scanned
+func (s *Service) recoverFailedProject(failure *gitlab.ProjectOutcome) (*lastKnownGood, *degradedPackage, bool) {
+	project := failure.Project
+
+	lkg, found := s.loadLastKnownGood(project.ID)
+	if !found {
+		s.logger.Printf("project %s has no last known good version, it won't be published", project.PathWithNamespace)
+		return nil, nil, false
+	}
+
+	s.logger.Printf(
+		"package %s is degraded, serving the last known good version from %s because %s",
+		lkg.Name,
+		lkg.Scanned.Format(time.RFC3339),
+		failure.Err,
+	)
+
+	degraded := &degradedPackage{
+		Name:        lkg.Name,
+		ProjectId:   project.ID,
+		Project:     project.PathWithNamespace,
+		Error:       failure.Err.Error(),
+		LastScanned: lkg.Scanned,
+	}
+
+	return lkg, degraded, true
+}
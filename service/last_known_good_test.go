@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	goGitlab "github.com/xanzy/go-gitlab"
+
+	"github.com/atomicptr/gitlab-composer-integration/composer"
+	"github.com/atomicptr/gitlab-composer-integration/gitlab"
+)
+
+func createLastKnownGoodTestServer(composerJson *string) *httptest.Server {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/api/v4/projects", func(writer http.ResponseWriter, _ *http.Request) {
+		_, _ = fmt.Fprint(writer, `[{"id": 1, "path_with_namespace": "atomicptr/test", "default_branch": "master"}]`)
+	})
+	mux.HandleFunc("/api/v4/projects/1", func(writer http.ResponseWriter, _ *http.Request) {
+		_, _ = fmt.Fprint(writer, `{"id": 1, "path_with_namespace": "atomicptr/test", "default_branch": "master"}`)
+	})
+	mux.HandleFunc("/api/v4/projects/1/repository/files/composer.json", func(writer http.ResponseWriter, _ *http.Request) {
+		_, _ = fmt.Fprintf(
+			writer,
+			`{"file_name": "composer.json", "encoding": "base64", "content": "%s"}`,
+			base64.StdEncoding.EncodeToString([]byte(*composerJson)),
+		)
+	})
+	mux.HandleFunc("/api/v4/projects/1/repository/commits", func(writer http.ResponseWriter, _ *http.Request) {
+		_, _ = fmt.Fprint(writer, `[{"id": "1234"}]`)
+	})
+	mux.HandleFunc("/api/v4/projects/1/repository/tags", func(writer http.ResponseWriter, _ *http.Request) {
+		_, _ = fmt.Fprint(writer, `[{"name": "v1.0.0", "commit": {"id": "1234"}}]`)
+	})
+
+	return httptest.NewServer(mux)
+}
+
+func TestCreateComposerRepositoryFallsBackToLastKnownGood(t *testing.T) {
+	composerJson := `{"name": "atomicptr/test"}`
+
+	server := createLastKnownGoodTestServer(&composerJson)
+	defer server.Close()
+
+	s := newTestService(server.URL)
+
+	first, err := s.fetchComposerData(context.Background())
+	assert.Nil(t, err)
+	assert.Nil(t, s.swapGeneration(first))
+	assert.Contains(t, first.providers, "atomicptr/test")
+	assert.Empty(t, first.degraded)
+
+	// somebody pushed a broken composer.json
+	composerJson = `{"name": "atomicptr/test",`
+
+	second, err := s.fetchComposerData(context.Background())
+	assert.Nil(t, err)
+	assert.Nil(t, s.swapGeneration(second))
+	assert.EqualValues(t, first.providers["atomicptr/test"], second.providers["atomicptr/test"])
+
+	degraded := s.currentDegradedPackages()
+	assert.Len(t, degraded, 1)
+	assert.EqualValues(t, "atomicptr/test", degraded[0].Name)
+	assert.EqualValues(t, 1, degraded[0].ProjectId)
+	assert.NotEmpty(t, degraded[0].Error)
+}
+
+func TestCreateComposerRepositoryWithoutLastKnownGood(t *testing.T) {
+	composerJson := `{"name": "atomicptr/test",`
+
+	server := createLastKnownGoodTestServer(&composerJson)
+	defer server.Close()
+
+	s := newTestService(server.URL)
+
+	gen, err := s.fetchComposerData(context.Background())
+	assert.Nil(t, err)
+	assert.Empty(t, gen.providers)
+	assert.Empty(t, gen.degraded)
+}
+
+func TestRecoverFailedProject(t *testing.T) {
+	s := newTestService("https://gitlab.com")
+
+	failure := &gitlab.ProjectOutcome{
+		Project: &goGitlab.Project{ID: 42, PathWithNamespace: "atomicptr/test"},
+		Err:     fmt.Errorf("tags are unavailable"),
+	}
+
+	_, _, found := s.recoverFailedProject(failure)
+	assert.False(t, found)
+
+	scanned := time.Now().Add(-time.Hour)
+	s.storeLastKnownGood(42, &lastKnownGood{
+		Name:     "atomicptr/test",
+		Vendor:   "atomicptr",
+		Packages: composer.PackageInfo{"dev-master": composer.VersionInfo{Version: "dev-master"}},
+		Scanned:  scanned,
+	})
+
+	lkg, degraded, found := s.recoverFailedProject(failure)
+	assert.True(t, found)
+	assert.Contains(t, lkg.Packages, "dev-master")
+	assert.EqualValues(t, "atomicptr/test", degraded.Name)
+	assert.EqualValues(t, "tags are unavailable", degraded.Error)
+	assert.True(t, scanned.Equal(degraded.LastScanned))
+}
+
+func TestPublishProjectFails(t *testing.T) {
+	s := newTestService("https://gitlab.com")
+
+	project := &gitlab.ComposerProject{
+		Name:    "atomicptr/test",
+		Vendor:  "atomicptr",
+		Project: &goGitlab.Project{ID: 1, PathWithNamespace: "atomicptr/test"},
+		Head:    &goGitlab.Commit{ID: "1234"},
+	}
+
+	// release dates after the year 9999 can't be encoded
+	released := time.Date(10000, 1, 1, 0, 0, 0, 0, time.UTC)
+	project.Head.CommittedDate = &released
+
+	gen := newGeneration(time.Now())
+	gen.report = newScanReport(gen.created, nil)
+
+	assert.False(t, s.publishProject(gen, project))
+	assert.Empty(t, gen.providers)
+
+	report := gen.report.project(1)
+	assert.EqualValues(t, scanStatusPublishFailed, report.Status)
+	assert.NotEmpty(t, report.Error)
+	assert.False(t, report.Degraded)
+
+	// the last known good version is published instead
+	s.storeLastKnownGood(1, &lastKnownGood{
+		Name:     "atomicptr/test",
+		Vendor:   "atomicptr",
+		Packages: composer.PackageInfo{"dev-master": composer.VersionInfo{Version: "dev-master"}},
+		Scanned:  time.Now().Add(-time.Hour),
+	})
+
+	gen = newGeneration(time.Now())
+	gen.report = newScanReport(gen.created, nil)
+
+	assert.False(t, s.publishProject(gen, project))
+	assert.Contains(t, gen.providers, "atomicptr/test")
+	assert.Len(t, gen.degraded, 1)
+	assert.True(t, gen.report.project(1).Degraded)
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andybalholm/brotli"
+)
+
+const (
+	encodingBrotli = "br"
+	encodingGzip   = "gzip"
+)
+
+// the metadata of packages.json changes with every refresh so clients have to revalidate it
+const indexCacheControl = "private, no-cache"
+
+// provider urls contain the hash of their content, so they never change
+const providerCacheControl = "private, max-age=31536000, immutable"
+
+// serveMetadata writes the data with caching headers, answers conditional requests with 304 and compresses
+// the response if the client supports it. The etag is expected to be unique for the data (e.g. a sha256 hash).
+func (s *Service) serveMetadata(
+	writer http.ResponseWriter,
+	request *http.Request,
+	data []byte,
+	etag string,
+	modTime time.Time,
+	cacheControl string,
+) {
+	encoding := negotiateEncoding(request.Header.Get("Accept-Encoding"))
+
+	// every representation needs its own strong etag
+	if encoding != "" {
+		etag = fmt.Sprintf("%s-%s", etag, encoding)
+		writer.Header().Set("Content-Encoding", encoding)
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.Header().Set("Cache-Control", cacheControl)
+	writer.Header().Set("ETag", strconv.Quote(etag))
+	writer.Header().Add("Vary", "Accept-Encoding")
+
+	// handles If-None-Match, If-Modified-Since and range requests for us, the body is only compressed if it
+	// is actually sent
+	body := &encodedBody{data: data, encoding: encoding}
+	http.ServeContent(writer, request, "", modTime, body)
+
+	if body.err != nil {
+		s.logger.Printf("could not compress response with %s because %s", encoding, body.err)
+	}
+}
+
+// encodedBody compresses the data on first access, this way answering conditional requests with 304 does not
+// pay for compressing the body
+type encodedBody struct {
+	data     []byte
+	encoding string
+	reader   *bytes.Reader
+	err      error
+}
+
+func (b *encodedBody) load() error {
+	if b.reader == nil && b.err == nil {
+		body, err := encodeBody(b.data, b.encoding)
+		b.reader = bytes.NewReader(body)
+		b.err = err
+	}
+	return b.err
+}
+
+func (b *encodedBody) Read(p []byte) (int, error) {
+	if err := b.load(); err != nil {
+		return 0, err
+	}
+	return b.reader.Read(p)
+}
+
+func (b *encodedBody) Seek(offset int64, whence int) (int64, error) {
+	if err := b.load(); err != nil {
+		return 0, err
+	}
+	return b.reader.Seek(offset, whence)
+}
+
+func encodeBody(data []byte, encoding string) ([]byte, error) {
+	var buffer bytes.Buffer
+	var encoder io.WriteCloser
+
+	switch encoding {
+	case encodingBrotli:
+		encoder = brotli.NewWriterLevel(&buffer, brotli.DefaultCompression)
+	case encodingGzip:
+		encoder = gzip.NewWriter(&buffer)
+	default:
+		return data, nil
+	}
+
+	if _, err := encoder.Write(data); err != nil {
+		return nil, err
+	}
+
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// negotiateEncoding returns the supported content encoding the client prefers according to the given
+// Accept-Encoding header, brotli wins if the client has no preference. Returns an empty string if the
+// response should not be compressed.
+func negotiateEncoding(acceptEncoding string) string {
+	qualities := make(map[string]float64)
+	wildcardQuality := -1.0
+
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		fields := strings.Split(part, ";")
+		name := strings.ToLower(strings.TrimSpace(fields[0]))
+		quality := 1.0
+
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				value, err := strconv.ParseFloat(param[2:], 64)
+				if err == nil {
+					quality = value
+				}
+			}
+		}
+
+		if name == "*" {
+			wildcardQuality = quality
+		} else {
+			qualities[name] = quality
+		}
+	}
+
+	bestEncoding := ""
+	bestQuality := 0.0
+
+	// ordered by preference
+	for _, encoding := range []string{encodingBrotli, encodingGzip} {
+		quality, ok := qualities[encoding]
+		if !ok {
+			quality = wildcardQuality
+		}
+
+		if quality > bestQuality {
+			bestEncoding = encoding
+			bestQuality = quality
+		}
+	}
+
+	return bestEncoding
+}
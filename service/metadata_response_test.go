@@ -0,0 +1,131 @@
+package service
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/stretchr/testify/assert"
+)
+
+const testMetadata = `{"packages": {"atomicptr/test": {}}}`
+
+func serveTestMetadata(s *Service, headers map[string]string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest("GET", "/packages.json", nil)
+	for name, value := range headers {
+		request.Header.Set(name, value)
+	}
+
+	recorder := httptest.NewRecorder()
+	s.serveMetadata(
+		recorder,
+		request,
+		[]byte(testMetadata),
+		"1234",
+		time.Date(2020, 4, 1, 12, 0, 0, 0, time.UTC),
+		indexCacheControl,
+	)
+	return recorder
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	values := map[string]string{
+		"":                       "",
+		"identity":               "",
+		"gzip":                   "gzip",
+		"gzip, deflate":          "gzip",
+		"gzip, deflate, br":      "br",
+		"br;q=0.5, gzip":         "gzip",
+		"br;q=0, gzip;q=0":       "",
+		"*":                      "br",
+		"br;q=0, *":              "gzip",
+		"GZIP;q=0.8, br;q=0.1":   "gzip",
+		"gzip;q=invalid, br;q=0": "gzip",
+	}
+
+	for value, expected := range values {
+		assert.EqualValues(t, expected, negotiateEncoding(value), value)
+	}
+}
+
+func TestServeMetadata(t *testing.T) {
+	s := newTestService("https://gitlab.com")
+
+	recorder := serveTestMetadata(s, nil)
+
+	assert.EqualValues(t, http.StatusOK, recorder.Code)
+	assert.EqualValues(t, testMetadata, recorder.Body.String())
+	assert.EqualValues(t, `"1234"`, recorder.Header().Get("ETag"))
+	assert.EqualValues(t, indexCacheControl, recorder.Header().Get("Cache-Control"))
+	assert.EqualValues(t, "application/json", recorder.Header().Get("Content-Type"))
+	assert.EqualValues(t, "Wed, 01 Apr 2020 12:00:00 GMT", recorder.Header().Get("Last-Modified"))
+}
+
+func TestServeMetadataGzip(t *testing.T) {
+	s := newTestService("https://gitlab.com")
+
+	recorder := serveTestMetadata(s, map[string]string{"Accept-Encoding": "gzip"})
+
+	assert.EqualValues(t, "gzip", recorder.Header().Get("Content-Encoding"))
+	assert.EqualValues(t, `"1234-gzip"`, recorder.Header().Get("ETag"))
+
+	reader, err := gzip.NewReader(recorder.Body)
+	assert.Nil(t, err)
+	data, err := ioutil.ReadAll(reader)
+	assert.Nil(t, err)
+	assert.EqualValues(t, testMetadata, string(data))
+}
+
+func TestServeMetadataBrotli(t *testing.T) {
+	s := newTestService("https://gitlab.com")
+
+	recorder := serveTestMetadata(s, map[string]string{"Accept-Encoding": "gzip, br"})
+
+	assert.EqualValues(t, "br", recorder.Header().Get("Content-Encoding"))
+
+	data, err := ioutil.ReadAll(brotli.NewReader(recorder.Body))
+	assert.Nil(t, err)
+	assert.EqualValues(t, testMetadata, string(data))
+}
+
+func TestServeMetadataIfNoneMatch(t *testing.T) {
+	s := newTestService("https://gitlab.com")
+
+	recorder := serveTestMetadata(s, map[string]string{"If-None-Match": `"1234"`})
+	assert.EqualValues(t, http.StatusNotModified, recorder.Code)
+	assert.Empty(t, recorder.Body.String())
+
+	// the etag of the gzip representation does not match the uncompressed one
+	recorder = serveTestMetadata(s, map[string]string{"If-None-Match": `"1234-gzip"`})
+	assert.EqualValues(t, http.StatusOK, recorder.Code)
+
+	recorder = serveTestMetadata(s, map[string]string{"If-None-Match": `"1234-br"`, "Accept-Encoding": "br"})
+	assert.EqualValues(t, http.StatusNotModified, recorder.Code)
+	assert.EqualValues(t, `"1234-br"`, recorder.Header().Get("ETag"))
+	assert.Empty(t, recorder.Body.String())
+}
+
+func TestEncodedBodyIsCompressedOnFirstAccess(t *testing.T) {
+	body := &encodedBody{data: []byte(testMetadata), encoding: encodingGzip}
+	assert.Nil(t, body.reader)
+
+	reader, err := gzip.NewReader(body)
+	assert.Nil(t, err)
+	data, err := ioutil.ReadAll(reader)
+	assert.Nil(t, err)
+	assert.EqualValues(t, testMetadata, string(data))
+}
+
+func TestServeMetadataIfModifiedSince(t *testing.T) {
+	s := newTestService("https://gitlab.com")
+
+	recorder := serveTestMetadata(s, map[string]string{"If-Modified-Since": "Wed, 01 Apr 2020 12:00:00 GMT"})
+	assert.EqualValues(t, http.StatusNotModified, recorder.Code)
+
+	recorder = serveTestMetadata(s, map[string]string{"If-Modified-Since": "Tue, 31 Mar 2020 12:00:00 GMT"})
+	assert.EqualValues(t, http.StatusOK, recorder.Code)
+}
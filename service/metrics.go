@@ -0,0 +1,186 @@
+package service
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const metricsNamespace = "gci"
+
+type metrics struct {
+	registry              *prometheus.Registry
+	requests              *prometheus.CounterVec
+	requestDuration       *prometheus.HistogramVec
+	cacheLookups          *prometheus.CounterVec
+	gitlabRequests        *prometheus.CounterVec
+	gitlabErrors          prometheus.Counter
+	gitlabRequestDuration prometheus.Histogram
+	scanDuration          prometheus.Histogram
+	publishedPackages     prometheus.Gauge
+	publishedVersions     prometheus.Gauge
+	skippedProjects       prometheus.Gauge
+	degradedPackages      prometheus.Gauge
+}
+
+func newMetrics(lastRefresh func() time.Time) *metrics {
+	m := &metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "http_requests_total",
+			Help:      "Total amount of HTTP requests per handler and status code.",
+		}, []string{"handler", "code"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Latency of HTTP requests per handler.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"handler"}),
+		cacheLookups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "cache_lookups_total",
+			Help:      "Total amount of cache lookups per kind (project, hash) and result (hit, miss).",
+		}, []string{"kind", "result"}),
+		gitlabRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "gitlab_requests_total",
+			Help:      "Total amount of requests to the Gitlab API per status code.",
+		}, []string{"code"}),
+		gitlabErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "gitlab_request_errors_total",
+			Help:      "Total amount of failed requests to the Gitlab API, looking up missing files is not a failure.",
+		}),
+		gitlabRequestDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "gitlab_request_duration_seconds",
+			Help:      "Latency of requests to the Gitlab API.",
+			Buckets:   prometheus.DefBuckets,
+		}),
+		scanDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "scan_duration_seconds",
+			Help:      "Duration of scanning Gitlab for composer projects.",
+			Buckets:   prometheus.ExponentialBuckets(1, 2, 12),
+		}),
+		publishedPackages: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "published_packages",
+			Help:      "Amount of packages published by the last successful refresh.",
+		}),
+		publishedVersions: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "published_versions",
+			Help:      "Amount of package versions published by the last successful refresh.",
+		}),
+		skippedProjects: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "skipped_projects",
+			Help:      "Amount of projects skipped by the last successful refresh.",
+		}),
+		degradedPackages: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "degraded_packages",
+			Help:      "Amount of packages published with their last known good version by the last successful refresh.",
+		}),
+	}
+
+	startTime := time.Now()
+
+	m.registry.MustRegister(
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		prometheus.NewGoCollector(),
+		m.requests,
+		m.requestDuration,
+		m.cacheLookups,
+		m.gitlabRequests,
+		m.gitlabErrors,
+		m.gitlabRequestDuration,
+		m.scanDuration,
+		m.publishedPackages,
+		m.publishedVersions,
+		m.skippedProjects,
+		m.degradedPackages,
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "seconds_since_last_successful_refresh",
+			Help:      "Time since the last successful refresh (or since the start of the service if there was none).",
+		}, func() float64 {
+			last := lastRefresh()
+			if last.IsZero() {
+				last = startTime
+			}
+			return time.Since(last).Seconds()
+		}),
+	)
+
+	return m
+}
+
+// handler exposes all metrics in the Prometheus exposition format
+func (m *metrics) handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// instrumentHandler records request counts and latencies of the given handler
+func (m *metrics) instrumentHandler(name string, handlerFunc http.HandlerFunc) http.HandlerFunc {
+	return promhttp.InstrumentHandlerCounter(
+		m.requests.MustCurryWith(prometheus.Labels{"handler": name}),
+		promhttp.InstrumentHandlerDuration(
+			m.requestDuration.MustCurryWith(prometheus.Labels{"handler": name}),
+			handlerFunc,
+		),
+	)
+}
+
+// instrumentTransport records request counts, errors and latencies of all requests to Gitlab
+func (m *metrics) instrumentTransport(next http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(request *http.Request) (*http.Response, error) {
+		start := time.Now()
+		response, err := next.RoundTrip(request)
+		m.gitlabRequestDuration.Observe(time.Since(start).Seconds())
+
+		if err != nil {
+			m.gitlabRequests.WithLabelValues("error").Inc()
+			m.gitlabErrors.Inc()
+			return response, err
+		}
+
+		m.gitlabRequests.WithLabelValues(strconv.Itoa(response.StatusCode)).Inc()
+		if isGitlabError(request, response) {
+			m.gitlabErrors.Inc()
+		}
+
+		return response, nil
+	})
+}
+
+// isGitlabError returns true if the request failed, files which don't exist are expected (e.g. projects without
+// a composer.json) and don't count as failures
+func isGitlabError(request *http.Request, response *http.Response) bool {
+	if response.StatusCode == http.StatusNotFound && strings.Contains(request.URL.Path, "/repository/files/") {
+		return false
+	}
+
+	return response.StatusCode >= http.StatusBadRequest
+}
+
+// observeCacheLookup records whether a cache lookup of the given kind was successful
+func (m *metrics) observeCacheLookup(kind string, found bool) {
+	result := "miss"
+	if found {
+		result = "hit"
+	}
+	m.cacheLookups.WithLabelValues(kind, result).Inc()
+}
+
+type roundTripperFunc func(request *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(request *http.Request) (*http.Response, error) {
+	return f(request)
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsObserveCacheLookup(t *testing.T) {
+	m := newMetrics(time.Now)
+
+	m.observeCacheLookup("hash", true)
+	m.observeCacheLookup("hash", false)
+	m.observeCacheLookup("hash", false)
+
+	assert.EqualValues(t, 1, testutil.ToFloat64(m.cacheLookups.WithLabelValues("hash", "hit")))
+	assert.EqualValues(t, 2, testutil.ToFloat64(m.cacheLookups.WithLabelValues("hash", "miss")))
+}
+
+func TestMetricsInstrumentTransport(t *testing.T) {
+	m := newMetrics(time.Now)
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.URL.Path != "/" {
+			http.NotFound(writer, request)
+		}
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: m.instrumentTransport(http.DefaultTransport)}
+
+	response, err := client.Get(server.URL)
+	assert.Nil(t, err)
+	_ = response.Body.Close()
+
+	response, err = client.Get(server.URL + "/api/v4/projects/42")
+	assert.Nil(t, err)
+	_ = response.Body.Close()
+
+	// a missing composer.json is expected and not an error
+	response, err = client.Get(server.URL + "/api/v4/projects/42/repository/files/composer.json")
+	assert.Nil(t, err)
+	_ = response.Body.Close()
+
+	assert.EqualValues(t, 1, testutil.ToFloat64(m.gitlabRequests.WithLabelValues("200")))
+	assert.EqualValues(t, 2, testutil.ToFloat64(m.gitlabRequests.WithLabelValues("404")))
+	assert.EqualValues(t, 1, testutil.ToFloat64(m.gitlabErrors))
+}
+
+func TestMetricsHandler(t *testing.T) {
+	m := newMetrics(func() time.Time { return time.Time{} })
+
+	m.instrumentHandler("/test", func(writer http.ResponseWriter, request *http.Request) {})(
+		httptest.NewRecorder(),
+		httptest.NewRequest("GET", "/test", nil),
+	)
+
+	recorder := httptest.NewRecorder()
+	m.handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+	assert.EqualValues(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `gci_http_requests_total{code="200",handler="/test"} 1`)
+	assert.Contains(t, recorder.Body.String(), "gci_seconds_since_last_successful_refresh")
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// the amount of refs per project whose files are cached, files of older refs are removed
+const maxCachedFileRefs = 20
+
+// packageFileNames are the files looked for (in order) for each kind of documentation file
+var packageFileNames = map[string][]string{
+	"readme":    {"README.md", "readme.md", "Readme.md", "README.markdown", "README", "README.txt"},
+	"changelog": {"CHANGELOG.md", "changelog.md", "CHANGELOG", "CHANGES.md", "CHANGES", "HISTORY.md"},
+	"license":   {"LICENSE", "LICENSE.md", "LICENSE.txt", "license", "license.md", "COPYING"},
+}
+
+// packageFile is a documentation file of a package at a certain ref, refs are commits so the file never changes
+type packageFile struct {
+	Path    string `json:"path"`
+	Found   bool   `json:"found"`
+	Content []byte `json:"content"`
+}
+
+func getPackageFileCacheIdentifier(projectId int, ref, kind string) string {
+	return fmt.Sprintf("file:%d:%s:%s", projectId, ref, kind)
+}
+
+// getPackageFileRefsCacheIdentifier returns the key of the refs whose files are cached, the most recent first
+func getPackageFileRefsCacheIdentifier(projectId int) string {
+	return fmt.Sprintf("files:%d", projectId)
+}
+
+// packageFile returns the documentation file (readme, changelog or license) of the project at the given ref
+func (s *Service) packageFile(ctx context.Context, projectId int, ref, kind string) (*packageFile, error) {
+	fileNames, ok := packageFileNames[kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown kind of file %s", kind)
+	}
+
+	cacheKey := getPackageFileCacheIdentifier(projectId, ref, kind)
+
+	if data, found := s.getFromCache(cacheKey); found {
+		var cached packageFile
+		if err := json.Unmarshal(data, &cached); err == nil {
+			return &cached, nil
+		}
+	}
+
+	file := &packageFile{}
+
+	for _, fileName := range fileNames {
+		content, found, err := s.gitlabClient.GetRawFile(ctx, projectId, fileName, ref)
+		if err != nil {
+			return nil, err
+		}
+
+		if found {
+			file = &packageFile{Path: fileName, Found: true, Content: content}
+			break
+		}
+	}
+
+	s.trackPackageFileRef(projectId, ref)
+
+	data, err := json.Marshal(file)
+	if err == nil {
+		err = s.cache.Set(cacheKey, data)
+	}
+	if err != nil {
+		s.logger.Println(errors.Wrapf(err, "could not cache %s of project %d", kind, projectId))
+	}
+
+	return file, nil
+}
+
+// trackPackageFileRef marks the ref as the most recent one of the project, the files of refs exceeding
+// maxCachedFileRefs are removed
+func (s *Service) trackPackageFileRef(projectId int, ref string) {
+	var evicted []string
+
+	err := s.cache.Update(getPackageFileRefsCacheIdentifier(projectId), func(value []byte) ([]byte, error) {
+		var refs []string
+		if value != nil {
+			if err := json.Unmarshal(value, &refs); err != nil {
+				return nil, err
+			}
+		}
+
+		updated := []string{ref}
+		for _, cached := range refs {
+			if cached != ref {
+				updated = append(updated, cached)
+			}
+		}
+
+		evicted = nil
+		if len(updated) > maxCachedFileRefs {
+			evicted = updated[maxCachedFileRefs:]
+			updated = updated[:maxCachedFileRefs]
+		}
+
+		return json.Marshal(updated)
+	})
+	if err != nil {
+		s.logger.Println(errors.Wrapf(err, "could not track cached files of project %d", projectId))
+		return
+	}
+
+	for _, evictedRef := range evicted {
+		if err := s.cache.DeletePrefix(getPackageFileCacheIdentifier(projectId, evictedRef, "")); err != nil {
+			s.logger.Println(errors.Wrapf(err, "could not remove cached files of project %d", projectId))
+		}
+	}
+}
+
+// removePackageFiles removes all cached files of a project which does not exist anymore
+func (s *Service) removePackageFiles(projectId int) {
+	err := s.cache.DeletePrefix(fmt.Sprintf("file:%d:", projectId))
+	if err == nil {
+		err = s.cache.Delete(getPackageFileRefsCacheIdentifier(projectId))
+	}
+	if err != nil {
+		s.logger.Println(errors.Wrapf(err, "could not remove cached files of project %d", projectId))
+	}
+}
+
+// packageFileContentType returns the content type of the file, all documentation files are expected to be text
+func packageFileContentType(path string) string {
+	lower := strings.ToLower(path)
+	if strings.HasSuffix(lower, ".md") || strings.HasSuffix(lower, ".markdown") {
+		return "text/markdown; charset=utf-8"
+	}
+	return "text/plain; charset=utf-8"
+}
@@ -0,0 +1,175 @@
+package service
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/atomicptr/gitlab-composer-integration/composer"
+	"github.com/atomicptr/gitlab-composer-integration/gitlab"
+)
+
+// catalogueEntry describes a package published by a generation, the details are taken from the composer.json
+// of the default branch
+type catalogueEntry struct {
+	Name        string   `json:"name"`
+	ProjectId   int      `json:"projectId"`
+	Project     string   `json:"project"`
+	Versions    []string `json:"versions"`
+	Degraded    bool     `json:"degraded,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Type        string   `json:"type,omitempty"`
+	Keywords    []string `json:"keywords,omitempty"`
+	License     []string `json:"license,omitempty"`
+	// Require and RequireDev map package names to their version constraints
+	Require    map[string]string `json:"require,omitempty"`
+	RequireDev map[string]string `json:"require-dev,omitempty"`
+	WebUrl     string            `json:"webUrl,omitempty"`
+	// Reference is the HEAD commit of the default branch
+	Reference string `json:"reference,omitempty"`
+	// Released maps versions to their release date
+	Released  map[string]time.Time `json:"released,omitempty"`
+	Abandoned bool                 `json:"abandoned,omitempty"`
+	// Replacement is the package which should be used instead of an abandoned one
+	Replacement string `json:"replacement,omitempty"`
+}
+
+// publishPackage adds the provider data of the package to the generation
+func publishPackage(gen *generation, entry *catalogueEntry, packages composer.PackageInfo) error {
+	packageData := composer.ProviderRepository{
+		Packages: map[string]composer.PackageInfo{entry.Name: packages},
+	}
+
+	data, err := json.Marshal(packageData)
+	if err != nil {
+		return errors.Wrapf(err, "could not cache project: %s", entry.Name)
+	}
+
+	hash, err := createHash(data)
+	if err != nil {
+		return errors.Wrap(err, "could not create sha256 hash")
+	}
+
+	entry.Versions = make([]string, 0, len(packages))
+	entry.Released = make(map[string]time.Time)
+
+	for version, info := range packages {
+		entry.Versions = append(entry.Versions, version)
+
+		if info.Time != nil {
+			entry.Released[version] = *info.Time
+		}
+	}
+	sort.Strings(entry.Versions)
+
+	gen.providers[entry.Name] = data
+	gen.hashes[entry.Name] = hash
+	gen.catalogue[entry.Name] = entry
+	return nil
+}
+
+// publishProject adds the scanned project to the generation if its vendor is allowed, returns false if it was not
+// published. The last known good version is published instead if the project itself can't be published.
+func (s *Service) publishProject(gen *generation, project *gitlab.ComposerProject) bool {
+	report := gen.report.project(project.Project.ID)
+
+	if !s.config.IsVendorAllowed(project.Vendor) {
+		report.Status = scanStatusVendorNotAllowed
+		return false
+	}
+
+	packages := createComposerPackageInfo(project, s.packageUidFunc(project.Project.ID), s.config.BaseUrl)
+
+	abandoned, replacement := project.Abandoned()
+
+	entry := &catalogueEntry{
+		Name:        project.Name,
+		ProjectId:   project.Project.ID,
+		Project:     project.Project.PathWithNamespace,
+		Description: project.Description(),
+		Type:        project.Type(),
+		Keywords:    project.Keywords(),
+		License:     project.License(),
+		Require:     project.Require(),
+		RequireDev:  project.RequireDev(),
+		WebUrl:      project.Project.WebURL,
+		Reference:   project.Head.ID,
+		Abandoned:   abandoned,
+		Replacement: replacement,
+	}
+
+	if err := publishPackage(gen, entry, packages); err != nil {
+		s.logger.Println(err)
+		report.Status = scanStatusPublishFailed
+		report.Error = err.Error()
+		s.publishLastKnownGood(gen, &gitlab.ProjectOutcome{Project: project.Project, Err: err})
+		return false
+	}
+
+	report.Status = scanStatusPublished
+
+	s.storeLastKnownGood(project.Project.ID, &lastKnownGood{
+		Name:      project.Name,
+		Vendor:    project.Vendor,
+		Packages:  packages,
+		Catalogue: entry,
+		Scanned:   gen.created,
+	})
+
+	return true
+}
+
+// publishLastKnownGood adds the last known good version of a project which could not be scanned to the
+// generation, this way a single broken project does not make its package disappear
+func (s *Service) publishLastKnownGood(gen *generation, failure *gitlab.ProjectOutcome) {
+	lkg, degraded, found := s.recoverFailedProject(failure)
+	if !found || !s.config.IsVendorAllowed(lkg.Vendor) {
+		return
+	}
+
+	if _, exists := gen.providers[lkg.Name]; exists {
+		return
+	}
+
+	entry := &catalogueEntry{Name: lkg.Name}
+	if lkg.Catalogue != nil {
+		entry = lkg.Catalogue
+	}
+
+	entry.ProjectId = failure.Project.ID
+	entry.Project = failure.Project.PathWithNamespace
+	entry.Degraded = true
+
+	if err := publishPackage(gen, entry, lkg.Packages); err != nil {
+		s.logger.Println(errors.Wrapf(err, "could not publish the last known good version of %s", lkg.Name))
+		return
+	}
+
+	gen.degraded = append(gen.degraded, degraded)
+	gen.report.project(failure.Project.ID).Degraded = true
+}
+
+// updatePublishMetrics sets the metrics describing the packages published by the generation
+func (s *Service) updatePublishMetrics(gen *generation) {
+	s.setPublishMetrics(gen.sortedCatalogue(), len(gen.degraded), gen.report)
+}
+
+// setPublishMetrics sets the metrics describing the published packages, the skipped projects are taken from the
+// report of the scan which created them
+func (s *Service) setPublishMetrics(catalogue []*catalogueEntry, degraded int, report *scanReport) {
+	versions := 0
+	for _, entry := range catalogue {
+		versions += len(entry.Versions)
+	}
+
+	s.metrics.publishedPackages.Set(float64(len(catalogue)))
+	s.metrics.publishedVersions.Set(float64(versions))
+	s.metrics.degradedPackages.Set(float64(degraded))
+	s.setDegradedPackageCount(degraded)
+
+	if report != nil {
+		s.metrics.skippedProjects.Set(float64(report.countSkipped()))
+	}
+}
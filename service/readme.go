@@ -0,0 +1,30 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"html/template"
+
+	"github.com/pkg/errors"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// raw HTML in readmes is escaped, this way readmes can't inject scripts into the web UI
+var markdown = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+// readme returns the rendered readme of the project at the given ref, the second return value is false if
+// the project has no readme. The file is shared with the docs endpoint.
+func (s *Service) readme(ctx context.Context, projectId int, ref string) (template.HTML, bool, error) {
+	file, err := s.packageFile(ctx, projectId, ref, "readme")
+	if err != nil || !file.Found {
+		return "", false, err
+	}
+
+	var buffer bytes.Buffer
+	if err := markdown.Convert(file.Content, &buffer); err != nil {
+		return "", false, errors.Wrapf(err, "could not render %s of project %d", file.Path, projectId)
+	}
+
+	return template.HTML(buffer.String()), true, nil
+}
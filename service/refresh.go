@@ -0,0 +1,290 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/atomicptr/gitlab-composer-integration/gitlab"
+)
+
+const refreshRequestCacheKey = "refresh-request"
+const scanProgressCacheKey = "scan-progress"
+
+const (
+	scanKindFull    = "full"
+	scanKindProject = "project"
+)
+
+// refreshRequest contains the refreshes requested via the admin API, it is stored in the cache so the
+// leader picks it up no matter which instance received the request
+type refreshRequest struct {
+	Full bool `json:"full"`
+	// Flush drops the generation served before the full refresh instead of keeping it for clients still
+	// using its index
+	Flush       bool      `json:"flush,omitempty"`
+	ProjectIds  []int     `json:"projectIds,omitempty"`
+	RequestedAt time.Time `json:"requestedAt"`
+}
+
+// scanProgress describes the running (or the last) scan
+type scanProgress struct {
+	Running    bool       `json:"running"`
+	Kind       string     `json:"kind,omitempty"`
+	Instance   string     `json:"instance,omitempty"`
+	StartedAt  *time.Time `json:"startedAt,omitempty"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	Scanned    int        `json:"scanned"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// queueRefresh requests a refresh of the given projects (or everything if full is true) and wakes up the
+// cache update handler of this instance
+func (s *Service) queueRefresh(full bool, projectIds ...int) (*refreshRequest, error) {
+	return s.queueRefreshRequest(&refreshRequest{Full: full, ProjectIds: projectIds})
+}
+
+// queueRefreshRequest merges the given refresh into the pending one and wakes up the cache update handler of
+// this instance. The pending request is updated atomically, this way concurrent requests received by different
+// instances don't overwrite each other.
+func (s *Service) queueRefreshRequest(queued *refreshRequest) (*refreshRequest, error) {
+	var request *refreshRequest
+
+	err := s.cache.Update(refreshRequestCacheKey, func(data []byte) ([]byte, error) {
+		request = &refreshRequest{}
+
+		if data != nil {
+			if err := json.Unmarshal(data, request); err != nil {
+				s.logger.Println(errors.Wrap(err, "could not read refresh request, replacing it"))
+				request = &refreshRequest{}
+			}
+		}
+
+		request.Full = request.Full || queued.Full
+		request.Flush = request.Flush || queued.Flush
+		request.RequestedAt = time.Now()
+
+		for _, projectId := range queued.ProjectIds {
+			if !containsInt(request.ProjectIds, projectId) {
+				request.ProjectIds = append(request.ProjectIds, projectId)
+			}
+		}
+
+		data, err := json.Marshal(request)
+		return data, errors.Wrap(err, "could not encode refresh request")
+	})
+
+	if err != nil {
+		return nil, errors.Wrap(err, "could not store refresh request")
+	}
+
+	select {
+	case s.refreshTrigger <- struct{}{}:
+	default:
+		// a refresh is already pending
+	}
+
+	return request, nil
+}
+
+func (s *Service) loadRefreshRequest() (*refreshRequest, bool) {
+	data, found := s.getFromCache(refreshRequestCacheKey)
+	if !found {
+		return nil, false
+	}
+
+	var request refreshRequest
+	if err := json.Unmarshal(data, &request); err != nil {
+		s.logger.Println(errors.Wrap(err, "could not read refresh request"))
+		return nil, false
+	}
+
+	return &request, true
+}
+
+// takeRefreshRequest returns the pending refresh request and removes it, requests queued in the meantime are
+// not lost
+func (s *Service) takeRefreshRequest() (*refreshRequest, bool) {
+	var taken []byte
+
+	err := s.cache.Update(refreshRequestCacheKey, func(data []byte) ([]byte, error) {
+		taken = data
+		return nil, nil
+	})
+	if err != nil {
+		s.logger.Println(errors.Wrap(err, "could not take refresh request"))
+		return nil, false
+	}
+
+	if taken == nil {
+		return nil, false
+	}
+
+	var request refreshRequest
+	if err := json.Unmarshal(taken, &request); err != nil {
+		s.logger.Println(errors.Wrap(err, "could not read refresh request"))
+		return nil, false
+	}
+
+	return &request, true
+}
+
+// refreshProjects rescans the given projects and swaps in a generation containing the results, all other
+// packages are taken from the current generation
+func (s *Service) refreshProjects(ctx context.Context, projectIds []int) error {
+	s.startScanProgress(scanKindProject)
+
+	gen, err := s.deriveGeneration(time.Now())
+	if err != nil {
+		s.finishScanProgress(err)
+		return errors.Wrap(err, "could not refresh projects")
+	}
+
+	previous := gen.sortedCatalogue()
+
+	report, found := s.loadScanReport()
+	if !found {
+		report = newScanReport(gen.scanned, nil)
+	}
+	gen.report = report
+	refreshed := 0
+
+	for i, projectId := range projectIds {
+		project, outcome, err := s.gitlabClient.ScanProject(ctx, projectId)
+		if ctx.Err() != nil {
+			s.finishScanProgress(ctx.Err())
+			return ctx.Err()
+		}
+
+		// the packages of deleted projects must not be published anymore
+		if errors.Cause(err) == gitlab.ErrProjectNotFound {
+			s.logger.Printf("project %d does not exist anymore, removing its packages", projectId)
+			refreshed++
+			gen.removeProject(projectId)
+			gen.report.remove(projectId)
+			s.removeLastKnownGood(projectId)
+			s.removePackageFiles(projectId)
+			s.updateScanProgress(i + 1)
+			continue
+		}
+
+		if err != nil {
+			s.logger.Println(errors.Wrapf(err, "could not refresh project %d", projectId))
+			continue
+		}
+
+		refreshed++
+		gen.removeProject(projectId)
+		gen.report.replace(outcome)
+
+		if project != nil {
+			s.publishProject(gen, project)
+		} else if outcome.Err != nil {
+			s.publishLastKnownGood(gen, outcome)
+		}
+
+		s.updateScanProgress(i + 1)
+	}
+
+	if refreshed == 0 {
+		err = errors.New("none of the projects could be scanned")
+		s.finishScanProgress(err)
+		return err
+	}
+
+	gen.index, err = gen.createIndex(s.config.IsAdvisoryDatabaseEnabled())
+	if err == nil {
+		err = s.swapGeneration(gen)
+	}
+
+	if err != nil {
+		s.finishScanProgress(err)
+		return errors.Wrap(err, "could not refresh projects")
+	}
+
+	s.storeScanReport(gen.report)
+	s.updatePublishMetrics(gen)
+	s.setLastRefresh(gen.created)
+	s.finishScanProgress(nil)
+	s.persistCacheInFile()
+	s.reportImpact(previous, gen.sortedCatalogue())
+
+	return nil
+}
+
+func (s *Service) startScanProgress(kind string) {
+	startedAt := time.Now()
+	s.storeScanProgress(&scanProgress{
+		Running:   true,
+		Kind:      kind,
+		Instance:  s.instanceId,
+		StartedAt: &startedAt,
+	})
+}
+
+// updateScanProgress records the amount of projects scanned so far
+func (s *Service) updateScanProgress(scanned int) {
+	progress, found := s.loadScanProgress()
+	if !found {
+		return
+	}
+
+	progress.Scanned = scanned
+	s.storeScanProgress(progress)
+}
+
+func (s *Service) finishScanProgress(err error) {
+	progress, found := s.loadScanProgress()
+	if !found {
+		return
+	}
+
+	finishedAt := time.Now()
+	progress.Running = false
+	progress.FinishedAt = &finishedAt
+
+	if err != nil {
+		progress.Error = err.Error()
+	}
+
+	s.storeScanProgress(progress)
+}
+
+func (s *Service) storeScanProgress(progress *scanProgress) {
+	data, err := json.Marshal(progress)
+	if err == nil {
+		err = s.cache.Set(scanProgressCacheKey, data)
+	}
+
+	if err != nil {
+		s.logger.Println(errors.Wrap(err, "could not store scan progress"))
+	}
+}
+
+// loadScanProgress returns the progress of the running scan (or the last one), it is shared between all
+// instances
+func (s *Service) loadScanProgress() (*scanProgress, bool) {
+	data, found := s.getFromCache(scanProgressCacheKey)
+	if !found {
+		return nil, false
+	}
+
+	var progress scanProgress
+	if err := json.Unmarshal(data, &progress); err != nil {
+		s.logger.Println(errors.Wrap(err, "could not read scan progress"))
+		return nil, false
+	}
+
+	return &progress, true
+}
+
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,218 @@
+package service
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/atomicptr/gitlab-composer-integration/composer"
+	"github.com/atomicptr/gitlab-composer-integration/gitlab"
+)
+
+const scanReportCacheKey = "scan-report"
+
+// in addition to the outcomes reported by the scan, these are decided by the service
+const (
+	scanStatusPublished        = "published"
+	scanStatusVendorNotAllowed = "vendor-not-allowed"
+	scanStatusPublishFailed    = "publish-failed"
+)
+
+// projectReport is the scan outcome of a single project
+type projectReport struct {
+	ProjectId int    `json:"projectId"`
+	Project   string `json:"project"`
+	Package   string `json:"package,omitempty"`
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+	// Diagnostics lists the problems of an invalid composer.json
+	Diagnostics []composer.ValidationError `json:"diagnostics,omitempty"`
+	// Degraded is true if the last known good version of the package is published instead
+	Degraded  bool      `json:"degraded,omitempty"`
+	ScannedAt time.Time `json:"scannedAt"`
+}
+
+// scanReport explains the outcome of the last scan for every project, this way developers can find out why
+// their package is missing
+type scanReport struct {
+	StartedAt  time.Time        `json:"startedAt"`
+	FinishedAt time.Time        `json:"finishedAt"`
+	Error      string           `json:"error,omitempty"`
+	Projects   []*projectReport `json:"projects"`
+	projectMap map[int]*projectReport
+}
+
+func newScanReport(startedAt time.Time, outcomes []*gitlab.ProjectOutcome) *scanReport {
+	report := &scanReport{
+		StartedAt:  startedAt,
+		Projects:   []*projectReport{},
+		projectMap: make(map[int]*projectReport),
+	}
+
+	for _, outcome := range outcomes {
+		project := &projectReport{
+			ProjectId:   outcome.Project.ID,
+			Project:     outcome.Project.PathWithNamespace,
+			Package:     outcome.Package,
+			Status:      string(outcome.Status),
+			Diagnostics: outcome.Diagnostics,
+			ScannedAt:   outcome.ScannedAt,
+		}
+
+		if outcome.Err != nil {
+			project.Error = outcome.Err.Error()
+		}
+
+		report.Projects = append(report.Projects, project)
+		report.projectMap[project.ProjectId] = project
+	}
+
+	sort.Slice(report.Projects, func(i, j int) bool {
+		return report.Projects[i].Project < report.Projects[j].Project
+	})
+
+	return report
+}
+
+// project returns the report of the given project, changes are reflected in the report
+func (r *scanReport) project(projectId int) *projectReport {
+	// reports read from the cache have no index yet
+	if r.projectMap == nil {
+		r.projectMap = make(map[int]*projectReport)
+		for _, project := range r.Projects {
+			r.projectMap[project.ProjectId] = project
+		}
+	}
+
+	if project, ok := r.projectMap[projectId]; ok {
+		return project
+	}
+
+	// every scanned project should be known, this is just a safety net
+	project := &projectReport{ProjectId: projectId}
+	r.Projects = append(r.Projects, project)
+	r.projectMap[projectId] = project
+	return project
+}
+
+// replace replaces the report of a single project with the given outcome
+func (r *scanReport) replace(outcome *gitlab.ProjectOutcome) *projectReport {
+	project := r.project(outcome.Project.ID)
+	*project = *newScanReport(outcome.ScannedAt, []*gitlab.ProjectOutcome{outcome}).Projects[0]
+	return project
+}
+
+// findProject returns the report of the given project
+func (r *scanReport) findProject(projectId int) (*projectReport, bool) {
+	for _, project := range r.Projects {
+		if project.ProjectId == projectId {
+			return project, true
+		}
+	}
+
+	return nil, false
+}
+
+// findPackage returns the report of the project the given package is published from, skipped projects are not
+// matched as they are no packages
+func (r *scanReport) findPackage(packageName string) (*projectReport, bool) {
+	if packageName == "" {
+		return nil, false
+	}
+
+	for _, project := range r.Projects {
+		if project.Package == packageName && !project.skipped() {
+			return project, true
+		}
+	}
+
+	return nil, false
+}
+
+// skipped returns true if the project was not considered to be a package
+func (p *projectReport) skipped() bool {
+	return p.Status == string(gitlab.ScanStatusNoComposerJson) || p.Status == scanStatusVendorNotAllowed
+}
+
+// public returns a copy of the report which can be shown to every user, errors might contain internal details and
+// are only available through the admin API
+func (p *projectReport) public() *projectReport {
+	public := *p
+	public.Error = ""
+	return &public
+}
+
+// countSkipped returns the amount of projects which have a composer.json but could not be published
+func (r *scanReport) countSkipped() int {
+	skipped := 0
+
+	for _, project := range r.Projects {
+		switch project.Status {
+		case string(gitlab.ScanStatusInvalidJson),
+			string(gitlab.ScanStatusMissingName),
+			string(gitlab.ScanStatusInvalidSchema),
+			string(gitlab.ScanStatusNoCommits),
+			scanStatusVendorNotAllowed,
+			scanStatusPublishFailed:
+			skipped++
+		}
+	}
+
+	return skipped
+}
+
+// remove removes the report of the given project
+func (r *scanReport) remove(projectId int) {
+	projects := r.Projects[:0]
+	for _, project := range r.Projects {
+		if project.ProjectId != projectId {
+			projects = append(projects, project)
+		}
+	}
+
+	r.Projects = projects
+	delete(r.projectMap, projectId)
+}
+
+// filter returns a copy of the report only containing projects with the given status
+func (r *scanReport) filter(status string) *scanReport {
+	filtered := *r
+	filtered.Projects = []*projectReport{}
+
+	for _, project := range r.Projects {
+		if project.Status == status {
+			filtered.Projects = append(filtered.Projects, project)
+		}
+	}
+
+	return &filtered
+}
+
+func (s *Service) storeScanReport(report *scanReport) {
+	data, err := json.Marshal(report)
+	if err == nil {
+		err = s.cache.Set(scanReportCacheKey, data)
+	}
+
+	if err != nil {
+		s.logger.Println(errors.Wrap(err, "could not store scan report"))
+	}
+}
+
+// loadScanReport returns the report of the last scan, it is shared between all instances
+func (s *Service) loadScanReport() (*scanReport, bool) {
+	data, found := s.getFromCache(scanReportCacheKey)
+	if !found {
+		return nil, false
+	}
+
+	var report scanReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		s.logger.Println(errors.Wrap(err, "could not read scan report"))
+		return nil, false
+	}
+
+	return &report, true
+}
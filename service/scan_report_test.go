@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScanReportOfPublishedProject(t *testing.T) {
+	composerJson := `{"name": "atomicptr/test"}`
+
+	server := createLastKnownGoodTestServer(&composerJson)
+	defer server.Close()
+
+	s := newTestService(server.URL)
+
+	gen, err := s.fetchComposerData(context.Background())
+	assert.Nil(t, err)
+	assert.Len(t, gen.report.Projects, 1)
+
+	project := gen.report.Projects[0]
+	assert.EqualValues(t, 1, project.ProjectId)
+	assert.EqualValues(t, "atomicptr/test", project.Project)
+	assert.EqualValues(t, "atomicptr/test", project.Package)
+	assert.EqualValues(t, scanStatusPublished, project.Status)
+	assert.False(t, project.ScannedAt.IsZero())
+	assert.False(t, gen.report.FinishedAt.IsZero())
+}
+
+func TestScanReportOfVendorNotAllowed(t *testing.T) {
+	composerJson := `{"name": "atomicptr/test"}`
+
+	server := createLastKnownGoodTestServer(&composerJson)
+	defer server.Close()
+
+	s := newTestService(server.URL)
+	s.config.VendorWhitelist = []string{"psr"}
+
+	gen, err := s.fetchComposerData(context.Background())
+	assert.Nil(t, err)
+	assert.Len(t, gen.report.Projects, 1)
+	assert.EqualValues(t, scanStatusVendorNotAllowed, gen.report.Projects[0].Status)
+}
+
+func TestScanReportOfInvalidProject(t *testing.T) {
+	composerJson := `{"name": "atomicptr/test",`
+
+	server := createLastKnownGoodTestServer(&composerJson)
+	defer server.Close()
+
+	s := newTestService(server.URL)
+
+	gen, err := s.fetchComposerData(context.Background())
+	assert.Nil(t, err)
+	assert.Len(t, gen.report.Projects, 1)
+	assert.EqualValues(t, "invalid-json", gen.report.Projects[0].Status)
+	assert.NotEmpty(t, gen.report.Projects[0].Error)
+	assert.False(t, gen.report.Projects[0].Degraded)
+}
+
+func TestScanReportOfInvalidSchema(t *testing.T) {
+	composerJson := `{"name": "AtomicPtr/Test"}`
+
+	server := createLastKnownGoodTestServer(&composerJson)
+	defer server.Close()
+
+	s := newTestService(server.URL)
+
+	gen, err := s.fetchComposerData(context.Background())
+	assert.Nil(t, err)
+	assert.Len(t, gen.report.Projects, 1)
+	assert.EqualValues(t, "invalid-schema", gen.report.Projects[0].Status)
+	assert.Len(t, gen.report.Projects[0].Diagnostics, 1)
+	assert.EqualValues(t, "name", gen.report.Projects[0].Diagnostics[0].Path)
+}
+
+func TestHandleScanReportEndpoint(t *testing.T) {
+	s := newTestService("https://gitlab.com")
+
+	recorder := httptest.NewRecorder()
+	s.handleScanReportEndpoint(recorder, httptest.NewRequest("GET", "/admin/scan-report", nil))
+	assert.EqualValues(t, http.StatusNotFound, recorder.Code)
+
+	s.storeScanReport(&scanReport{
+		StartedAt:  time.Now(),
+		FinishedAt: time.Now(),
+		Projects: []*projectReport{
+			{ProjectId: 1, Project: "atomicptr/a", Status: scanStatusPublished},
+			{ProjectId: 2, Project: "atomicptr/b", Status: "missing-name", Error: "composer.json has no name"},
+		},
+	})
+
+	recorder = httptest.NewRecorder()
+	s.handleScanReportEndpoint(recorder, httptest.NewRequest("GET", "/admin/scan-report", nil))
+	assert.EqualValues(t, http.StatusOK, recorder.Code)
+
+	var report scanReport
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &report))
+	assert.Len(t, report.Projects, 2)
+
+	recorder = httptest.NewRecorder()
+	s.handleScanReportEndpoint(recorder, httptest.NewRequest("GET", "/admin/scan-report?status=missing-name", nil))
+	assert.EqualValues(t, http.StatusOK, recorder.Code)
+
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &report))
+	assert.Len(t, report.Projects, 1)
+	assert.EqualValues(t, "atomicptr/b", report.Projects[0].Project)
+}
+
+func TestHandleScanReportEndpointListsDegradedPackages(t *testing.T) {
+	s := newTestService("https://gitlab.com")
+	s.storeScanReport(&scanReport{StartedAt: time.Now(), FinishedAt: time.Now()})
+
+	gen := createTestGeneration(time.Now(), "hash")
+	gen.degraded = []*degradedPackage{{Name: "atomicptr/test", ProjectId: 1, Error: "invalid composer.json"}}
+	assert.Nil(t, s.swapGeneration(gen))
+
+	recorder := httptest.NewRecorder()
+	s.handleScanReportEndpoint(recorder, httptest.NewRequest("GET", "/admin/scan-report", nil))
+	assert.EqualValues(t, http.StatusOK, recorder.Code)
+
+	var response struct {
+		Degraded []*degradedPackage `json:"degraded"`
+	}
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.Len(t, response.Degraded, 1)
+	assert.EqualValues(t, "invalid composer.json", response.Degraded[0].Error)
+}
+
+func TestFailedRefreshKeepsScanReport(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	s := newTestService(server.URL)
+	s.storeScanReport(&scanReport{
+		Projects: []*projectReport{{ProjectId: 1, Project: "atomicptr/a", Status: scanStatusPublished}},
+	})
+
+	s.refreshCache(false)
+
+	report, found := s.loadScanReport()
+	assert.True(t, found)
+	assert.NotEmpty(t, report.Error)
+	assert.Len(t, report.Projects, 1)
+}
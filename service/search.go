@@ -0,0 +1,149 @@
+package service
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// searchIndex holds the searchable text and the downloads of all packages of a generation, it is rebuilt once the
+// generation changes, which is after every refresh
+type searchIndex struct {
+	mutex        sync.Mutex
+	generationId string
+	entries      []*searchEntry
+}
+
+type searchEntry struct {
+	*catalogueEntry
+	name string
+	// text contains the lower case name, description, keywords and type
+	text string
+	// downloads is the total amount of downloads when the index was built
+	downloads uint64
+}
+
+// searchEntries returns the search index of the current generation
+func (s *Service) searchEntries() ([]*searchEntry, bool) {
+	generationId, found := s.currentGenerationId()
+	if !found {
+		return nil, false
+	}
+
+	s.searchIndex.mutex.Lock()
+	defer s.searchIndex.mutex.Unlock()
+
+	if s.searchIndex.generationId == generationId {
+		return s.searchIndex.entries, true
+	}
+
+	catalogue, found := s.loadCatalogue(generationId)
+	if !found {
+		return nil, false
+	}
+
+	downloads := s.totalDownloads()
+
+	entries := make([]*searchEntry, len(catalogue))
+	for i, entry := range catalogue {
+		text := []string{entry.Name, entry.Description, entry.Type}
+		text = append(text, entry.Keywords...)
+
+		entries[i] = &searchEntry{
+			catalogueEntry: entry,
+			name:           strings.ToLower(entry.Name),
+			text:           strings.ToLower(strings.Join(text, "\n")),
+			downloads:      downloads[entry.Name],
+		}
+	}
+
+	s.searchIndex.generationId = generationId
+	s.searchIndex.entries = entries
+
+	return entries, true
+}
+
+// totalDownloads returns the total amount of downloads of all packages, reading all statistics is expensive
+// so it is only done when the search index is rebuilt
+func (s *Service) totalDownloads() map[string]uint64 {
+	downloads := make(map[string]uint64)
+	if s.stats == nil {
+		return downloads
+	}
+
+	all, err := s.stats.All(time.Now())
+	if err != nil {
+		s.logger.Println(errors.Wrap(err, "could not read download statistics"))
+	}
+
+	for name, packageDownloads := range all {
+		downloads[name] = packageDownloads.Total
+	}
+
+	return downloads
+}
+
+// search returns all packages of the given type (any type if empty) which contain every term of the query in
+// their name, description, keywords or type. Packages whose name matches are ranked first.
+func search(entries []*searchEntry, query, packageType string) []*searchEntry {
+	terms := strings.Fields(strings.ToLower(query))
+
+	type match struct {
+		entry *searchEntry
+		rank  int
+	}
+
+	var matches []match
+
+	for _, entry := range entries {
+		if packageType != "" && entry.Type != packageType {
+			continue
+		}
+
+		if !containsAll(entry.text, terms) {
+			continue
+		}
+
+		rank := 2
+		if entry.name == strings.ToLower(strings.TrimSpace(query)) {
+			rank = 0
+		} else if containsAll(entry.name, terms) {
+			rank = 1
+		}
+
+		matches = append(matches, match{entry: entry, rank: rank})
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].rank != matches[j].rank {
+			return matches[i].rank < matches[j].rank
+		}
+		return matches[i].entry.Name < matches[j].entry.Name
+	})
+
+	results := make([]*searchEntry, len(matches))
+	for i, m := range matches {
+		results[i] = m.entry
+	}
+
+	return results
+}
+
+func containsAll(text string, terms []string) bool {
+	for _, term := range terms {
+		if !strings.Contains(text, term) {
+			return false
+		}
+	}
+	return true
+}
+
+// createNameFilter creates a case insensitive matcher for package names, "*" matches any characters
+func createNameFilter(filter string) *regexp.Regexp {
+	pattern := strings.Replace(regexp.QuoteMeta(filter), `\*`, ".*", -1)
+	return regexp.MustCompile("(?i)^" + pattern + "$")
+}
@@ -0,0 +1,183 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/atomicptr/gitlab-composer-integration/composer"
+	"github.com/atomicptr/gitlab-composer-integration/stats"
+)
+
+func createTestSearchGeneration() *generation {
+	gen := createTestGeneration(time.Now(), "hash")
+
+	entries := []*catalogueEntry{
+		{Name: "acme/logger", Type: "library", Description: "A PSR-3 logger", Keywords: []string{"log", "psr-3"}},
+		{Name: "acme/http-client", Type: "library", Description: "HTTP client with logging"},
+		{Name: "acme/website", Type: "project", Description: "The company website", Abandoned: true},
+		{Name: "other/logger-bundle", Type: "symfony-bundle", Description: "Integrates acme/logger"},
+	}
+
+	for _, entry := range entries {
+		gen.catalogue[entry.Name] = entry
+	}
+
+	return gen
+}
+
+func searchTestNames(entries []*searchEntry) []string {
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = entry.Name
+	}
+	return names
+}
+
+func TestSearch(t *testing.T) {
+	s := newTestService("https://gitlab.com")
+
+	_, found := s.searchEntries()
+	assert.False(t, found)
+
+	assert.Nil(t, s.swapGeneration(createTestSearchGeneration()))
+
+	entries, found := s.searchEntries()
+	assert.True(t, found)
+	assert.Len(t, entries, 4)
+
+	assert.EqualValues(
+		t,
+		[]string{"acme/logger", "other/logger-bundle", "acme/http-client"},
+		searchTestNames(search(entries, "LOG", "")),
+	)
+	assert.EqualValues(t, []string{"acme/logger"}, searchTestNames(search(entries, "psr-3", "")))
+	assert.EqualValues(t, []string{"acme/http-client"}, searchTestNames(search(entries, "client logging", "")))
+	assert.EqualValues(t, []string{"acme/website"}, searchTestNames(search(entries, "", "project")))
+	assert.Len(t, search(entries, "acme", ""), 4)
+	assert.Empty(t, search(entries, "unknown", ""))
+
+	// exact matches are ranked first
+	assert.EqualValues(t, "acme/logger", search(entries, "acme/logger", "")[0].Name)
+}
+
+func TestSearchEntriesAreRebuiltForNewGenerations(t *testing.T) {
+	s := newTestService("https://gitlab.com")
+	assert.Nil(t, s.swapGeneration(createTestSearchGeneration()))
+
+	entries, _ := s.searchEntries()
+	assert.Len(t, entries, 4)
+
+	gen := createTestSearchGeneration()
+	gen.created = gen.created.Add(time.Minute)
+	delete(gen.catalogue, "acme/website")
+	assert.Nil(t, s.swapGeneration(gen))
+
+	entries, _ = s.searchEntries()
+	assert.Len(t, entries, 3)
+}
+
+func TestHandleSearchEndpoint(t *testing.T) {
+	s := newTestService("https://gitlab.com")
+
+	recorder := httptest.NewRecorder()
+	s.handleSearchEndpoint(recorder, httptest.NewRequest("GET", "/search.json?q=logger", nil))
+	assert.EqualValues(t, http.StatusServiceUnavailable, recorder.Code)
+
+	assert.Nil(t, s.swapGeneration(createTestSearchGeneration()))
+
+	request := httptest.NewRequest("GET", "/search.json?q=logger&type=library", nil)
+	request.Host = "composer.example.com"
+
+	recorder = httptest.NewRecorder()
+	s.handleSearchEndpoint(recorder, request)
+	assert.EqualValues(t, http.StatusOK, recorder.Code)
+
+	var results composer.SearchResults
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &results))
+	assert.EqualValues(t, 1, results.Total)
+	assert.EqualValues(t, "acme/logger", results.Results[0].Name)
+	assert.EqualValues(t, "A PSR-3 logger", results.Results[0].Description)
+	assert.EqualValues(t, "http://composer.example.com/package/acme/logger", results.Results[0].Url)
+	assert.Nil(t, results.Results[0].Abandoned)
+
+	recorder = httptest.NewRecorder()
+	s.handleSearchEndpoint(recorder, httptest.NewRequest("GET", "/search.json?q=website", nil))
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &results))
+	assert.EqualValues(t, true, results.Results[0].Abandoned)
+
+	// the configured base url is preferred over the requested host
+	s.config.BaseUrl = "https://composer.example.com/"
+	request = httptest.NewRequest("GET", "/search.json?q=logger&type=library", nil)
+	request.Host = "attacker.example.org"
+
+	recorder = httptest.NewRecorder()
+	s.handleSearchEndpoint(recorder, request)
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &results))
+	assert.EqualValues(t, "https://composer.example.com/package/acme/logger", results.Results[0].Url)
+}
+
+func TestSearchDownloadsAreCachedPerGeneration(t *testing.T) {
+	s := newTestService("https://gitlab.com")
+
+	statsStore, err := s.openStats()
+	assert.Nil(t, err)
+	defer statsStore.Close()
+	s.stats = statsStore
+
+	assert.Nil(t, s.stats.RecordAll([]stats.Download{{Name: "acme/logger", Version: "v1.0.0"}}, time.Now()))
+	assert.Nil(t, s.swapGeneration(createTestSearchGeneration()))
+
+	downloads := func() uint64 {
+		entries, _ := s.searchEntries()
+		return search(entries, "acme/logger", "")[0].downloads
+	}
+
+	assert.EqualValues(t, 1, downloads())
+
+	// the statistics are only read again once there is a new generation
+	assert.Nil(t, s.stats.RecordAll([]stats.Download{{Name: "acme/logger", Version: "v1.0.0"}}, time.Now()))
+	assert.EqualValues(t, 1, downloads())
+
+	gen := createTestSearchGeneration()
+	gen.created = gen.created.Add(time.Minute)
+	assert.Nil(t, s.swapGeneration(gen))
+	assert.EqualValues(t, 2, downloads())
+}
+
+func TestHandlePackageListEndpoint(t *testing.T) {
+	s := newTestService("https://gitlab.com")
+	assert.Nil(t, s.swapGeneration(createTestSearchGeneration()))
+
+	list := func(url string) []string {
+		recorder := httptest.NewRecorder()
+		s.handlePackageListEndpoint(recorder, httptest.NewRequest("GET", url, nil))
+		assert.EqualValues(t, http.StatusOK, recorder.Code)
+
+		var packageList composer.PackageList
+		assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &packageList))
+		return packageList.PackageNames
+	}
+
+	assert.Len(t, list("/packages/list.json"), 4)
+	assert.EqualValues(t, []string{"other/logger-bundle"}, list("/packages/list.json?vendor=other"))
+	assert.EqualValues(
+		t,
+		[]string{"acme/http-client", "acme/logger"},
+		list("/packages/list.json?vendor=acme&type=library"),
+	)
+	assert.EqualValues(t, []string{"acme/logger", "other/logger-bundle"}, list("/packages/list.json?filter=*LOGGER*"))
+	assert.Empty(t, list("/packages/list.json?vendor=unknown"))
+}
+
+func TestCreateNameFilter(t *testing.T) {
+	assert.True(t, createNameFilter("acme/*").MatchString("acme/logger"))
+	assert.False(t, createNameFilter("acme/*").MatchString("other/acme"))
+	assert.True(t, createNameFilter("acme/logger").MatchString("ACME/Logger"))
+	assert.False(t, createNameFilter("acme/log").MatchString("acme/logger"))
+	assert.False(t, createNameFilter("acme.logger").MatchString("acme/logger"))
+}
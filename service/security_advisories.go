@@ -0,0 +1,114 @@
+package service
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/atomicptr/gitlab-composer-integration/advisories"
+)
+
+const securityAdvisoriesApiUrl = "/api/security-advisories/"
+
+// the advisory database is read again after this interval, this way changes are picked up without a restart
+const advisoryReloadInterval = time.Minute
+
+type advisoryDatabase struct {
+	mutex    sync.Mutex
+	db       *advisories.Database
+	loadedAt time.Time
+}
+
+// securityAdvisories returns the advisory database, it is reloaded if it is outdated. If the database can't be
+// loaded the previous one is used.
+func (s *Service) securityAdvisories() (*advisories.Database, error) {
+	s.advisoryDatabase.mutex.Lock()
+	defer s.advisoryDatabase.mutex.Unlock()
+
+	if s.advisoryDatabase.db != nil && time.Since(s.advisoryDatabase.loadedAt) < advisoryReloadInterval {
+		return s.advisoryDatabase.db, nil
+	}
+
+	db, err := advisories.Load(s.config.AdvisoryDatabasePath)
+	if err != nil {
+		if s.advisoryDatabase.db == nil {
+			return nil, err
+		}
+
+		s.logger.Println(errors.Wrap(err, "could not reload advisory database, using the previous one"))
+		db = s.advisoryDatabase.db
+	} else if previous := s.advisoryDatabase.db; previous == nil || isAdvisoryDatabaseChanged(previous, db) {
+		s.logger.Printf("loaded %d security advisories from %s", db.Count(), s.config.AdvisoryDatabasePath)
+	}
+
+	s.advisoryDatabase.db = db
+	s.advisoryDatabase.loadedAt = time.Now()
+
+	return db, nil
+}
+
+// isAdvisoryDatabaseChanged returns true if advisories were added, removed or modified
+func isAdvisoryDatabaseChanged(previous, db *advisories.Database) bool {
+	return previous.Count() != db.Count() || !previous.UpdatedAt().Equal(db.UpdatedAt())
+}
+
+// handleSecurityAdvisoriesEndpoint returns the advisories of the requested packages (packages[]=vendor/name), it
+// implements the security advisories API used by composer audit
+func (s *Service) handleSecurityAdvisoriesEndpoint(writer http.ResponseWriter, request *http.Request) {
+	s.logger.Printf("Request to \"%s\" from %s (%s)\n", request.URL, request.UserAgent(), request.RemoteAddr)
+
+	if !s.config.IsAdvisoryDatabaseEnabled() {
+		http.NotFound(writer, request)
+		return
+	}
+
+	if err := request.ParseForm(); err != nil {
+		http.Error(writer, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	var updatedSince time.Time
+	if value := request.Form.Get("updatedSince"); value != "" {
+		timestamp, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			http.Error(writer, "updatedSince has to be a unix timestamp", http.StatusBadRequest)
+			return
+		}
+		updatedSince = time.Unix(timestamp, 0)
+	}
+
+	db, err := s.securityAdvisories()
+	if err != nil {
+		s.logger.Println(errors.Wrap(err, "could not load advisory database"))
+		http.Error(writer, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	s.respondJson(writer, http.StatusOK, struct {
+		Advisories map[string][]*advisories.Advisory `json:"advisories"`
+	}{db.ForPackages(requestedPackages(request), updatedSince)})
+}
+
+// requestedPackages returns the package names of the request, composer sends them as packages[0]=vendor/name
+// while packages[]=vendor/name or packages=vendor/name is used by other clients
+func requestedPackages(request *http.Request) []string {
+	var keys []string
+	for key := range request.Form {
+		if key == "packages" || strings.HasPrefix(key, "packages[") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var packages []string
+	for _, key := range keys {
+		packages = append(packages, request.Form[key]...)
+	}
+
+	return packages
+}
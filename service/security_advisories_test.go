@@ -0,0 +1,169 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/atomicptr/gitlab-composer-integration/composer"
+)
+
+func createTestAdvisoryDatabase(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "gci-advisories")
+	assert.Nil(t, err)
+
+	assert.Nil(t, os.MkdirAll(filepath.Join(dir, "acme", "logger"), 0755))
+	assert.Nil(t, ioutil.WriteFile(
+		filepath.Join(dir, "acme", "logger", "2020-03-01.yaml"),
+		[]byte("title: Log injection\naffectedVersions: '>=1.0,<1.2.3'\nreportedAt: 2020-03-01 12:00:00\n"),
+		0644,
+	))
+
+	return dir, func() {
+		_ = os.RemoveAll(dir)
+	}
+}
+
+func requestTestAdvisories(s *Service, request *http.Request) (int, map[string][]map[string]interface{}) {
+	recorder := httptest.NewRecorder()
+	s.handleSecurityAdvisoriesEndpoint(recorder, request)
+
+	var response struct {
+		Advisories map[string][]map[string]interface{} `json:"advisories"`
+	}
+	_ = json.Unmarshal(recorder.Body.Bytes(), &response)
+
+	return recorder.Code, response.Advisories
+}
+
+func TestHandleSecurityAdvisoriesEndpointDisabled(t *testing.T) {
+	s := newTestService("https://gitlab.com")
+
+	code, _ := requestTestAdvisories(s, httptest.NewRequest("GET", securityAdvisoriesApiUrl, nil))
+	assert.EqualValues(t, http.StatusNotFound, code)
+}
+
+func TestHandleSecurityAdvisoriesEndpoint(t *testing.T) {
+	dir, cleanup := createTestAdvisoryDatabase(t)
+	defer cleanup()
+
+	s := newTestService("https://gitlab.com")
+	s.config.AdvisoryDatabasePath = dir
+
+	// composer posts the packages as form
+	form := url.Values{"packages[0]": {"acme/logger"}, "packages[1]": {"acme/unknown"}}
+	request := httptest.NewRequest("POST", securityAdvisoriesApiUrl, strings.NewReader(form.Encode()))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	code, advisories := requestTestAdvisories(s, request)
+	assert.EqualValues(t, http.StatusOK, code)
+	assert.Len(t, advisories, 1)
+	assert.EqualValues(t, "Log injection", advisories["acme/logger"][0]["title"])
+	assert.EqualValues(t, ">=1.0,<1.2.3", advisories["acme/logger"][0]["affectedVersions"])
+	assert.EqualValues(t, "2020-03-01 12:00:00", advisories["acme/logger"][0]["reportedAt"])
+
+	code, advisories = requestTestAdvisories(
+		s,
+		httptest.NewRequest("GET", securityAdvisoriesApiUrl+"?packages[]=acme/logger", nil),
+	)
+	assert.EqualValues(t, http.StatusOK, code)
+	assert.Len(t, advisories, 1)
+
+	updatedSince := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+	code, advisories = requestTestAdvisories(
+		s,
+		httptest.NewRequest("GET", securityAdvisoriesApiUrl+"?packages=acme/logger&updatedSince="+updatedSince, nil),
+	)
+	assert.EqualValues(t, http.StatusOK, code)
+	assert.Empty(t, advisories)
+
+	code, _ = requestTestAdvisories(
+		s,
+		httptest.NewRequest("GET", securityAdvisoriesApiUrl+"?packages=acme/logger&updatedSince=yesterday", nil),
+	)
+	assert.EqualValues(t, http.StatusBadRequest, code)
+}
+
+func TestSecurityAdvisoriesKeepsPreviousDatabase(t *testing.T) {
+	dir, cleanup := createTestAdvisoryDatabase(t)
+	defer cleanup()
+
+	s := newTestService("https://gitlab.com")
+	s.config.AdvisoryDatabasePath = dir
+
+	db, err := s.securityAdvisories()
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1, db.Count())
+
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "acme", "logger", "broken.yaml"), []byte("title: ["), 0644))
+	s.advisoryDatabase.loadedAt = time.Time{}
+
+	db, err = s.securityAdvisories()
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1, db.Count())
+
+	s = newTestService("https://gitlab.com")
+	s.config.AdvisoryDatabasePath = dir
+
+	_, err = s.securityAdvisories()
+	assert.NotNil(t, err)
+}
+
+func TestSecurityAdvisoriesLogsChangesOnly(t *testing.T) {
+	dir, cleanup := createTestAdvisoryDatabase(t)
+	defer cleanup()
+
+	var output bytes.Buffer
+	s := newTestService("https://gitlab.com")
+	s.config.AdvisoryDatabasePath = dir
+	s.logger = log.New(&output, "", 0)
+
+	_, err := s.securityAdvisories()
+	assert.Nil(t, err)
+	assert.Contains(t, output.String(), "loaded 1 security advisories")
+
+	// reloading an unchanged database is not logged
+	output.Reset()
+	s.advisoryDatabase.loadedAt = time.Time{}
+
+	_, err = s.securityAdvisories()
+	assert.Nil(t, err)
+	assert.Empty(t, output.String())
+
+	updated := time.Now().Add(time.Minute)
+	file := filepath.Join(dir, "acme", "logger", "2020-03-01.yaml")
+	assert.Nil(t, os.Chtimes(file, updated, updated))
+	s.advisoryDatabase.loadedAt = time.Time{}
+
+	_, err = s.securityAdvisories()
+	assert.Nil(t, err)
+	assert.Contains(t, output.String(), "loaded 1 security advisories")
+}
+
+func TestCreateIndexWithSecurityAdvisories(t *testing.T) {
+	gen := createTestGeneration(time.Now(), "hash")
+
+	data, err := gen.createIndex(false)
+	assert.Nil(t, err)
+	assert.NotContains(t, string(data), "security-advisories")
+
+	data, err = gen.createIndex(true)
+	assert.Nil(t, err)
+
+	var repository composer.Repository
+	assert.Nil(t, json.Unmarshal(data, &repository))
+	assert.EqualValues(t, securityAdvisoriesApiUrl, repository.SecurityAdvisories.ApiUrl)
+	assert.False(t, repository.SecurityAdvisories.Metadata)
+}
@@ -1,30 +1,54 @@
 package service
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"sync"
+	"time"
 
-	"github.com/patrickmn/go-cache"
 	"github.com/pkg/errors"
 
 	"github.com/atomicptr/gitlab-composer-integration/gitlab"
+	"github.com/atomicptr/gitlab-composer-integration/stats"
+	"github.com/atomicptr/gitlab-composer-integration/storage"
 )
 
 type Service struct {
-	config       Config
-	httpHandler  *http.ServeMux
-	httpServer   *http.Server
-	gitlabClient *gitlab.Client
-	cache        *cache.Cache
-	logger       *log.Logger
-	errorChan    chan error
-	running      bool
+	config           Config
+	httpHandler      *http.ServeMux
+	httpServer       *http.Server
+	gitlabClient     *gitlab.Client
+	cache            storage.Storage
+	stats            stats.Store
+	metrics          *metrics
+	logger           *log.Logger
+	errorChan        chan error
+	ctx              context.Context
+	cancel           context.CancelFunc
+	workers          sync.WaitGroup
+	refreshMutex     sync.RWMutex
+	lastRefresh      time.Time
+	syncedGeneration string
+	degradedPackages int
+	stale            bool
+	leader           bool
+	instanceId       string
+	indexReady       chan struct{}
+	indexReadyOnce   sync.Once
+	refreshTrigger   chan struct{}
+	gitlabStatus     gitlabStatus
+	searchIndex      searchIndex
+	advisoryDatabase advisoryDatabase
 }
 
 func New(config Config, logger *log.Logger, errorChan chan error) *Service {
 	handler := http.NewServeMux()
-	return &Service{
+	s := &Service{
 		config:      config,
 		httpHandler: handler,
 		httpServer: &http.Server{
@@ -33,19 +57,38 @@ func New(config Config, logger *log.Logger, errorChan chan error) *Service {
 			ReadTimeout:       config.HttpTimeout,
 			ReadHeaderTimeout: config.HttpTimeout,
 		},
-		gitlabClient: gitlab.New(
-			config.GitlabUrl,
-			config.GitlabToken,
-			logger,
-		),
-		cache:     cache.New(config.CacheExpireDuration, cache.NoExpiration),
-		logger:    logger,
-		errorChan: errorChan,
+		logger:         logger,
+		errorChan:      errorChan,
+		indexReady:     make(chan struct{}),
+		refreshTrigger: make(chan struct{}, 1),
+		instanceId:     createInstanceId(),
 	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.cache = s.newCacheStorage()
+	s.metrics = newMetrics(s.getLastRefresh)
+
+	// the configuration was validated before, in case it fails anyway the certificates are still verified
+	tlsConfig, err := gitlab.NewTlsConfig(config.GetGitlabTlsOptions())
+	if err != nil {
+		logger.Println(errors.Wrap(err, "could not configure TLS for gitlab, using the defaults"))
+	}
+
+	if config.GitlabInsecureSkipVerify {
+		logger.Println("verification of the gitlab certificate is disabled, this is insecure")
+	}
+
+	s.gitlabClient = gitlab.New(
+		config.GitlabUrl,
+		config.GitlabToken,
+		tlsConfig,
+		logger,
+		s.metrics.instrumentTransport,
+	)
+	return s
 }
 
 func (s *Service) Run() error {
-	if err := s.gitlabClient.Validate(); err != nil {
+	if err := s.gitlabClient.Validate(s.ctx); err != nil {
 		return errors.Wrap(err, "can't connect to gitlab")
 	}
 
@@ -53,31 +96,297 @@ func (s *Service) Run() error {
 		s.logger.Println("your Gitlab token is empty, you can only see public repositories this way")
 	}
 
+	if err := s.openCache(); err != nil {
+		return err
+	}
+
 	// if no cache option is set, flush all caches...
 	if s.config.NoCache {
-		s.cache.Flush()
+		if err := s.flushCacheOnStart(); err != nil {
+			return err
+		}
 	}
 
 	s.restoreFileCacheIfItExists()
 
-	s.running = true
-	go s.cacheUpdateHandler()
+	statsStore, err := s.openStats()
+	if err != nil {
+		return errors.Wrap(err, "can't open download statistics")
+	}
+	s.stats = statsStore
+
+	s.startWorker(s.leadershipHandler)
+	s.startWorker(s.cacheUpdateHandler)
+
+	s.handleFunc("/", s.handleWebIndexEndpoint)
+	s.handleFunc("/package/", s.handlePackagePageEndpoint)
+	s.httpHandler.HandleFunc("/healthz", s.metrics.instrumentHandler("/healthz", s.handleHealthzEndpoint))
+	s.httpHandler.HandleFunc("/readyz", s.metrics.instrumentHandler("/readyz", s.handleReadyzEndpoint))
+	s.handleFunc("/packages.json", s.handlePackagesJsonEndpoint)
+	s.handleFunc("/p", s.handleProviderEndpoint)
+	s.handleFunc("/search.json", s.handleSearchEndpoint)
+	s.handleFunc("/packages/list.json", s.handlePackageListEndpoint)
+	s.handleFunc("/dependencies.json", s.handleDependenciesEndpoint)
+	s.handleFunc("/dependencies.dot", s.handleDependenciesEndpoint)
+	s.handleFunc("/dependencies.mmd", s.handleDependenciesEndpoint)
+	s.handleFunc("/impact.json", s.handleImpactEndpoint)
+	s.handleFunc(securityAdvisoriesApiUrl, s.handleSecurityAdvisoriesEndpoint)
+	s.handleFunc(docsUrlPrefix, s.handleDocsEndpoint)
+	s.handleFunc("/notify", s.handleNotifyEndpoint)
+	s.handleFunc("/stats", s.handleStatsEndpoint)
+	s.handleAdminFunc("/admin/scan-report", s.handleScanReportEndpoint)
+	s.handleAdminFunc("/admin/refresh", s.handleAdminRefreshEndpoint)
+	s.handleAdminFunc("/admin/flush", s.handleAdminFlushEndpoint)
+	s.handleAdminFunc("/admin/packages", s.handleAdminPackagesEndpoint)
+	s.handleAdminFunc("/admin/progress", s.handleAdminProgressEndpoint)
+	s.handleFunc("/metrics", s.metrics.handler().ServeHTTP)
+
+	if s.config.IsTlsEnabled() {
+		reloader, err := newTlsReloader(s.config)
+		if err != nil {
+			return errors.Wrap(err, "can't configure TLS")
+		}
+
+		s.httpServer.TLSConfig = reloader.tlsConfig()
+		s.startWorker(func() {
+			s.tlsReloadHandler(reloader)
+		})
+
+		// the certificates are provided by the TLS config
+		err = s.httpServer.ListenAndServeTLS("", "")
+	} else {
+		err = s.httpServer.ListenAndServe()
+	}
+
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
+// openCache acquires the cache for this instance, caches which can't be shared fail if another instance is
+// using them already
+func (s *Service) openCache() error {
+	opener, ok := s.cache.(storage.Opener)
+	if !ok {
+		return nil
+	}
+
+	err := opener.Open()
+	if errors.Cause(err) == storage.ErrIncompatibleFile {
+		// e.g. the cache file written by the memory backend before switching to bolt
+		cachePath := s.getCacheDatabasePath()
+		s.logger.Printf("cache database %s can't be used (%s), discarding it and creating a new one", cachePath, err)
+		if err := os.Remove(cachePath); err != nil {
+			return errors.Wrap(err, "could not remove incompatible cache database")
+		}
+		err = opener.Open()
+	}
+
+	return errors.Wrap(err, "can't open cache")
+}
 
-	username, password := s.config.GetHttpCredentials()
+// flushCacheOnStart removes everything from the cache, a cache shared with other instances is kept because they
+// still serve it and a full refresh is requested instead
+func (s *Service) flushCacheOnStart() error {
+	if _, shared := s.cache.(storage.Elector); shared {
+		s.logger.Println("the cache is shared with other instances and not flushed, requesting a full refresh instead")
+		_, err := s.queueRefresh(true)
+		return errors.Wrap(err, "can't request a full refresh")
+	}
 
-	s.httpHandler.Handle("/", http.RedirectHandler("/packages.json", http.StatusMovedPermanently))
-	s.httpHandler.HandleFunc("/packages.json", basicAuth(username, password, s.handlePackagesJsonEndpoint))
-	s.httpHandler.HandleFunc("/p", basicAuth(username, password, s.handleProviderEndpoint))
-	s.httpHandler.HandleFunc("/notify", basicAuth(username, password, s.handleNotifyEndpoint))
-	return s.httpServer.ListenAndServe()
+	return errors.Wrap(s.cache.Flush(), "can't flush cache")
+}
+
+// startWorker runs the given function in the background, Stop waits for all workers to finish
+func (s *Service) startWorker(worker func()) {
+	s.workers.Add(1)
+	go func() {
+		defer s.workers.Done()
+		worker()
+	}()
+}
+
+// waitForWorkers waits until all workers finished or the context is done
+func (s *Service) waitForWorkers(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "background tasks did not finish in time")
+	}
 }
 
+// Stop shuts the service down gracefully: running scans are canceled, in-flight requests are drained within
+// the configured shutdown timeout and the cache is persisted one last time.
 func (s *Service) Stop() error {
-	s.running = false
-	err := s.httpServer.Close()
-	if err != nil {
+	// cancels scans and all other background tasks
+	s.cancel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.ShutdownTimeout)
+	defer cancel()
+
+	shutdownErr := s.httpServer.Shutdown(ctx)
+	if shutdownErr != nil {
+		shutdownErr = errors.Wrap(shutdownErr, "could not drain in-flight requests")
+	}
+
+	if err := s.waitForWorkers(ctx); err != nil {
+		s.logger.Println(err)
+	}
+
+	s.persistCacheInFile()
+	s.releaseLeadership()
+
+	if err := s.cache.Close(); err != nil {
 		return err
 	}
 
-	return nil
+	if s.stats != nil {
+		if err := s.stats.Close(); err != nil {
+			return err
+		}
+	}
+
+	return shutdownErr
+}
+
+// handleFunc registers the handler for the given pattern, protected by the configured authentication
+// methods and instrumented with metrics
+func (s *Service) handleFunc(pattern string, handlerFunc http.HandlerFunc) {
+	s.httpHandler.HandleFunc(
+		pattern,
+		s.metrics.instrumentHandler(pattern, withAuthentication(s.authenticators(), handlerFunc)),
+	)
+}
+
+// handleAdminFunc registers the handler of the admin API for the given pattern, it is only accessible with
+// the admin credentials and disabled if there are none
+func (s *Service) handleAdminFunc(pattern string, handlerFunc http.HandlerFunc) {
+	username, password := s.config.GetAdminCredentials()
+	if username == "" || password == "" {
+		handlerFunc = func(writer http.ResponseWriter, _ *http.Request) {
+			http.Error(writer, "the admin API is disabled because there are no admin credentials", http.StatusForbidden)
+		}
+	}
+
+	s.httpHandler.HandleFunc(
+		pattern,
+		s.metrics.instrumentHandler(pattern, basicAuth(username, password, handlerFunc)),
+	)
+}
+
+// authenticators returns all configured authentication methods, a request has to pass any of them
+func (s *Service) authenticators() []authenticator {
+	var authenticators []authenticator
+
+	if username, password := s.config.GetHttpCredentials(); username != "" && password != "" {
+		authenticators = append(authenticators, basicAuthenticator(username, password))
+	}
+
+	if s.config.TlsClientCaFile != "" {
+		authenticators = append(authenticators, clientCertificateAuthenticator(s.config.TlsClientIdentities))
+	}
+
+	return authenticators
+}
+
+func (s *Service) getLastRefresh() time.Time {
+	s.refreshMutex.RLock()
+	defer s.refreshMutex.RUnlock()
+	return s.lastRefresh
+}
+
+func (s *Service) setLastRefresh(lastRefresh time.Time) {
+	s.refreshMutex.Lock()
+	defer s.refreshMutex.Unlock()
+	s.lastRefresh = lastRefresh
+}
+
+// getSyncedGenerationId returns the id of the generation the state of this instance (e.g. the metrics) describes
+func (s *Service) getSyncedGenerationId() string {
+	s.refreshMutex.RLock()
+	defer s.refreshMutex.RUnlock()
+	return s.syncedGeneration
+}
+
+func (s *Service) setSyncedGenerationId(generationId string) {
+	s.refreshMutex.Lock()
+	defer s.refreshMutex.Unlock()
+	s.syncedGeneration = generationId
+}
+
+// getDegradedPackageCount returns the amount of packages of the current generation which are published with
+// their last known good version
+func (s *Service) getDegradedPackageCount() int {
+	s.refreshMutex.RLock()
+	defer s.refreshMutex.RUnlock()
+	return s.degradedPackages
+}
+
+func (s *Service) setDegradedPackageCount(degradedPackages int) {
+	s.refreshMutex.Lock()
+	defer s.refreshMutex.Unlock()
+	s.degradedPackages = degradedPackages
+}
+
+// isStale returns true if the last refresh failed and the previous generation is being served
+func (s *Service) isStale() bool {
+	s.refreshMutex.RLock()
+	defer s.refreshMutex.RUnlock()
+	return s.stale
+}
+
+func (s *Service) setStale(stale bool) {
+	s.refreshMutex.Lock()
+	defer s.refreshMutex.Unlock()
+	s.stale = stale
+}
+
+// isLeaderInstance returns true if this instance was elected to refresh the cache
+func (s *Service) isLeaderInstance() bool {
+	s.refreshMutex.RLock()
+	defer s.refreshMutex.RUnlock()
+	return s.leader
+}
+
+func (s *Service) setLeader(leader bool) {
+	s.refreshMutex.Lock()
+	defer s.refreshMutex.Unlock()
+	s.leader = leader
+}
+
+// markIndexReady signals that the first index has been built or restored from file
+func (s *Service) markIndexReady() {
+	s.indexReadyOnce.Do(func() {
+		close(s.indexReady)
+	})
+}
+
+func (s *Service) isIndexReady() bool {
+	select {
+	case <-s.indexReady:
+		return true
+	default:
+		return false
+	}
+}
+
+// createInstanceId creates an id unique to this instance of the service, used for the leader election
+func createInstanceId() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+
+	return fmt.Sprintf("%s-%s", hostname, hex.EncodeToString(suffix))
 }
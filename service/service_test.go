@@ -0,0 +1,81 @@
+package service
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testDir contains the files written by services created with newTestService, e.g. the persisted cache
+var testDir string
+
+func TestMain(m *testing.M) {
+	dir, err := ioutil.TempDir("", "gci-service")
+	if err != nil {
+		panic(err)
+	}
+	testDir = dir
+
+	code := m.Run()
+
+	_ = os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func TestStopCancelsWorkersAndPersistsCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gci-service")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	s := newTestService("https://gitlab.com")
+	s.config.CacheFilePath = path.Join(dir, "cache")
+	s.config.ShutdownTimeout = time.Second
+
+	assert.Nil(t, s.swapGeneration(createTestGeneration(time.Now(), "hash")))
+
+	workerCanceled := false
+	s.startWorker(func() {
+		<-s.ctx.Done()
+		workerCanceled = true
+	})
+
+	assert.Nil(t, s.Stop())
+	assert.True(t, workerCanceled)
+
+	_, err = os.Stat(s.config.CacheFilePath)
+	assert.Nil(t, err)
+}
+
+func TestStopWithBlockedWorker(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gci-service")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	s := newTestService("https://gitlab.com")
+	s.config.CacheFilePath = path.Join(dir, "cache")
+	s.config.ShutdownTimeout = 10 * time.Millisecond
+
+	blocked := make(chan struct{})
+	defer close(blocked)
+
+	s.startWorker(func() {
+		<-blocked
+	})
+
+	// the shutdown timeout has to be respected even if a worker does not react
+	done := make(chan struct{})
+	go func() {
+		_ = s.Stop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("stop did not respect the shutdown timeout")
+	}
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// the interval in which the certificate files are checked for changes
+const tlsReloadInterval = 10 * time.Second
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// tlsReloader keeps the server certificate (and client CA) up to date with the files on disk, this way
+// renewed certificates are picked up without a restart
+type tlsReloader struct {
+	certFile     string
+	keyFile      string
+	clientCaFile string
+	minVersion   uint16
+	mutex        sync.RWMutex
+	modTimes     map[string]time.Time
+	certificate  *tls.Certificate
+	clientCas    *x509.CertPool
+}
+
+func newTlsReloader(config Config) (*tlsReloader, error) {
+	reloader := &tlsReloader{
+		certFile:     config.TlsCertFile,
+		keyFile:      config.TlsKeyFile,
+		clientCaFile: config.TlsClientCaFile,
+		minVersion:   tlsVersions[config.TlsMinVersion],
+		modTimes:     make(map[string]time.Time),
+	}
+
+	if _, err := reloader.reload(); err != nil {
+		return nil, err
+	}
+
+	return reloader, nil
+}
+
+// reload reads the certificate files again if any of them changed, returns true if they were reloaded
+func (r *tlsReloader) reload() (bool, error) {
+	modTimes := make(map[string]time.Time)
+	changed := false
+
+	for _, file := range []string{r.certFile, r.keyFile, r.clientCaFile} {
+		if file == "" {
+			continue
+		}
+
+		info, err := os.Stat(file)
+		if err != nil {
+			return false, errors.Wrapf(err, "could not read %s", file)
+		}
+
+		modTimes[file] = info.ModTime()
+		if !info.ModTime().Equal(r.modTimes[file]) {
+			changed = true
+		}
+	}
+
+	if !changed {
+		return false, nil
+	}
+
+	certificate, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return false, errors.Wrap(err, "could not load TLS certificate")
+	}
+
+	var clientCas *x509.CertPool
+
+	if r.clientCaFile != "" {
+		data, err := ioutil.ReadFile(r.clientCaFile)
+		if err != nil {
+			return false, errors.Wrap(err, "could not read TLS client CA")
+		}
+
+		clientCas = x509.NewCertPool()
+		if !clientCas.AppendCertsFromPEM(data) {
+			return false, errors.Errorf("TLS client CA file %s contains no certificates", r.clientCaFile)
+		}
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.modTimes = modTimes
+	r.certificate = &certificate
+	r.clientCas = clientCas
+
+	return true, nil
+}
+
+func (r *tlsReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.certificate, nil
+}
+
+func (r *tlsReloader) getClientCas() *x509.CertPool {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.clientCas
+}
+
+// tlsConfig creates the server configuration which always uses the latest certificates
+func (r *tlsReloader) tlsConfig() *tls.Config {
+	config := &tls.Config{
+		MinVersion:     r.minVersion,
+		GetCertificate: r.getCertificate,
+	}
+
+	if r.clientCaFile != "" {
+		// clients without a certificate can still authenticate via basic auth
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+		config.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			clientConfig := config.Clone()
+			clientConfig.GetConfigForClient = nil
+			clientConfig.ClientCAs = r.getClientCas()
+			return clientConfig, nil
+		}
+	}
+
+	return config
+}
+
+// tlsReloadHandler periodically checks the certificate files for changes
+func (s *Service) tlsReloadHandler(reloader *tlsReloader) {
+	ticker := time.NewTicker(tlsReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		reloaded, err := reloader.reload()
+		if err != nil {
+			s.logger.Println(errors.Wrap(err, "could not reload TLS certificates, keep using the previous ones"))
+			continue
+		}
+
+		if reloaded {
+			s.logger.Println("reloaded TLS certificates")
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testCertificate struct {
+	certificate *x509.Certificate
+	key         *ecdsa.PrivateKey
+	certPem     []byte
+	keyPem      []byte
+}
+
+func createTestCertificate(t *testing.T, commonName string, parent *testCertificate) *testCertificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+
+	parentCertificate, parentKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+	} else {
+		parentCertificate, parentKey = parent.certificate, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parentCertificate, &key.PublicKey, parentKey)
+	assert.Nil(t, err)
+
+	certificate, err := x509.ParseCertificate(der)
+	assert.Nil(t, err)
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.Nil(t, err)
+
+	return &testCertificate{
+		certificate: certificate,
+		key:         key,
+		certPem:     pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPem:      pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}),
+	}
+}
+
+func writeTestCertificate(t *testing.T, dir, name string, certificate *testCertificate) (string, string) {
+	certFile := path.Join(dir, name+".crt")
+	keyFile := path.Join(dir, name+".key")
+	assert.Nil(t, ioutil.WriteFile(certFile, certificate.certPem, 0600))
+	assert.Nil(t, ioutil.WriteFile(keyFile, certificate.keyPem, 0600))
+	return certFile, keyFile
+}
+
+func TestTlsReloader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gci-tls")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	ca := createTestCertificate(t, "ca", nil)
+	certFile, keyFile := writeTestCertificate(t, dir, "server", createTestCertificate(t, "first", ca))
+
+	reloader, err := newTlsReloader(Config{TlsCertFile: certFile, TlsKeyFile: keyFile, TlsMinVersion: "1.2"})
+	assert.Nil(t, err)
+	assert.EqualValues(t, tls.VersionTLS12, reloader.tlsConfig().MinVersion)
+
+	reloaded, err := reloader.reload()
+	assert.Nil(t, err)
+	assert.False(t, reloaded)
+
+	// replace the certificate
+	writeTestCertificate(t, dir, "server", createTestCertificate(t, "second", ca))
+	future := time.Now().Add(time.Minute)
+	assert.Nil(t, os.Chtimes(certFile, future, future))
+
+	reloaded, err = reloader.reload()
+	assert.Nil(t, err)
+	assert.True(t, reloaded)
+
+	certificate, err := reloader.getCertificate(nil)
+	assert.Nil(t, err)
+
+	leaf, err := x509.ParseCertificate(certificate.Certificate[0])
+	assert.Nil(t, err)
+	assert.EqualValues(t, "second", leaf.Subject.CommonName)
+}
+
+func TestTlsReloaderInvalidFiles(t *testing.T) {
+	_, err := newTlsReloader(Config{TlsCertFile: "/does/not/exist.crt", TlsKeyFile: "/does/not/exist.key"})
+	assert.NotNil(t, err)
+}
+
+func TestClientCertificateAuthentication(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gci-tls")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	ca := createTestCertificate(t, "ca", nil)
+	caFile, _ := writeTestCertificate(t, dir, "ca", ca)
+	certFile, keyFile := writeTestCertificate(t, dir, "server", createTestCertificate(t, "server", ca))
+
+	reloader, err := newTlsReloader(Config{
+		TlsCertFile:     certFile,
+		TlsKeyFile:      keyFile,
+		TlsClientCaFile: caFile,
+	})
+	assert.Nil(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+
+	handler := withAuthentication(
+		[]authenticator{basicAuthenticator("username", "password"), clientCertificateAuthenticator([]string{"ci-runner"})},
+		func(writer http.ResponseWriter, request *http.Request) {},
+	)
+
+	server := &http.Server{Handler: handler}
+	go func() {
+		_ = server.Serve(tls.NewListener(listener, reloader.tlsConfig()))
+	}()
+	defer server.Close()
+
+	rootCas := x509.NewCertPool()
+	rootCas.AddCert(ca.certificate)
+
+	request := func(clientCertificate *testCertificate) int {
+		tlsConfig := &tls.Config{RootCAs: rootCas}
+
+		if clientCertificate != nil {
+			pair, err := tls.X509KeyPair(clientCertificate.certPem, clientCertificate.keyPem)
+			assert.Nil(t, err)
+			tlsConfig.Certificates = []tls.Certificate{pair}
+		}
+
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+
+		response, err := client.Get("https://" + listener.Addr().String())
+		assert.Nil(t, err)
+		_ = response.Body.Close()
+
+		return response.StatusCode
+	}
+
+	assert.EqualValues(t, http.StatusUnauthorized, request(nil))
+	assert.EqualValues(t, http.StatusUnauthorized, request(createTestCertificate(t, "someone-else", ca)))
+	assert.EqualValues(t, http.StatusOK, request(createTestCertificate(t, "ci-runner", ca)))
+}
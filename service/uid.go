@@ -0,0 +1,77 @@
+package service
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// uids are limited to 53 bits, this way they are represented exactly by every JSON implementation
+const uidMask = 1<<53 - 1
+
+// packageUidFunc returns the uid of the given version of a package
+type packageUidFunc func(version string) int64
+
+func getUidCacheIdentifier(projectId int, version string) string {
+	return fmt.Sprintf("uid:%d:%s", projectId, version)
+}
+
+func getUidOwnerCacheIdentifier(uid int64) string {
+	return fmt.Sprintf("uid-owner:%d", uid)
+}
+
+// deriveUid derives the uid of a project version, the attempt is increased if the uid is already taken
+// by another version
+func deriveUid(projectId int, version string, attempt int) int64 {
+	hasher := fnv.New64a()
+
+	if attempt == 0 {
+		_, _ = fmt.Fprintf(hasher, "%d:%s", projectId, version)
+	} else {
+		_, _ = fmt.Fprintf(hasher, "%d:%s:%d", projectId, version, attempt)
+	}
+
+	return int64(hasher.Sum64() & uidMask)
+}
+
+func (s *Service) packageUidFunc(projectId int) packageUidFunc {
+	return func(version string) int64 {
+		return s.packageUid(projectId, version)
+	}
+}
+
+// packageUid returns the uid of the given project version. The uid is derived from the project id and the
+// version and persisted once assigned, this way it stays stable across refreshes, restarts and instances.
+func (s *Service) packageUid(projectId int, version string) int64 {
+	uidKey := getUidCacheIdentifier(projectId, version)
+
+	if data, found := s.getFromCache(uidKey); found {
+		if uid, err := strconv.ParseInt(string(data), 10, 64); err == nil {
+			return uid
+		}
+	}
+
+	owner := []byte(uidKey)
+
+	for attempt := 0; ; attempt++ {
+		uid := deriveUid(projectId, version, attempt)
+		ownerKey := getUidOwnerCacheIdentifier(uid)
+
+		currentOwner, taken := s.getFromCache(ownerKey)
+		if taken && string(currentOwner) != string(owner) {
+			continue
+		}
+
+		err := s.cache.Set(ownerKey, owner)
+		if err == nil {
+			err = s.cache.Set(uidKey, []byte(strconv.FormatInt(uid, 10)))
+		}
+		if err != nil {
+			s.logger.Println(errors.Wrapf(err, "could not persist uid of %d (%s)", projectId, version))
+		}
+
+		return uid
+	}
+}
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeriveUid(t *testing.T) {
+	uid := deriveUid(42, "v1.0.0", 0)
+
+	assert.EqualValues(t, uid, deriveUid(42, "v1.0.0", 0))
+	assert.NotEqual(t, uid, deriveUid(42, "v1.0.1", 0))
+	assert.NotEqual(t, uid, deriveUid(43, "v1.0.0", 0))
+	assert.NotEqual(t, uid, deriveUid(42, "v1.0.0", 1))
+	assert.True(t, uid >= 0 && uid <= uidMask)
+}
+
+func TestPackageUidIsStable(t *testing.T) {
+	s := newTestService("https://gitlab.com")
+
+	uid := s.packageUid(42, "v1.0.0")
+	assert.EqualValues(t, deriveUid(42, "v1.0.0", 0), uid)
+	assert.EqualValues(t, uid, s.packageUid(42, "v1.0.0"))
+
+	// another instance (or a restart with an empty cache) derives the same uid
+	other := newTestService("https://gitlab.com")
+	assert.EqualValues(t, uid, other.packageUid(42, "v1.0.0"))
+}
+
+func TestPackageUidCollision(t *testing.T) {
+	s := newTestService("https://gitlab.com")
+
+	// pretend the derived uid is already owned by another version
+	taken := deriveUid(42, "v1.0.0", 0)
+	assert.Nil(t, s.cache.Set(getUidOwnerCacheIdentifier(taken), []byte(getUidCacheIdentifier(1, "v9.9.9"))))
+
+	uid := s.packageUid(42, "v1.0.0")
+	assert.EqualValues(t, deriveUid(42, "v1.0.0", 1), uid)
+
+	// the assigned uid is persisted
+	assert.EqualValues(t, uid, s.packageUid(42, "v1.0.0"))
+}
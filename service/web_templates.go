@@ -0,0 +1,132 @@
+package service
+
+// the layout shared by all pages of the web UI, it has no external assets
+const webLayoutTemplate = `{{define "layout"}}<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{template "title" .}}</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif; margin: 0;
+	color: #24292e; background: #f6f8fa; line-height: 1.5; }
+header { background: #292961; color: #fff; padding: 1em 2em; }
+header a { color: #fff; text-decoration: none; font-weight: bold; }
+main { max-width: 960px; margin: 0 auto; padding: 1em 2em; }
+a { color: #1f5fbf; }
+section { background: #fff; border: 1px solid #e1e4e8; border-radius: 4px; padding: 1em 1.5em; margin-bottom: 1em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { text-align: left; padding: 0.3em 0.5em; border-bottom: 1px solid #e1e4e8; vertical-align: top; }
+pre, code { font-family: SFMono-Regular, Consolas, Menlo, monospace; font-size: 0.9em; }
+pre { background: #f6f8fa; padding: 0.8em; overflow: auto; border-radius: 4px; }
+.muted { color: #6a737d; }
+.warning { background: #fff5b1; border-color: #e4d06b; }
+.tag { display: inline-block; background: #e1e4e8; border-radius: 3px; padding: 0 0.4em; font-size: 0.85em; }
+nav.vendors a { margin-right: 0.8em; }
+</style>
+</head>
+<body>
+<header><a href="/">Composer Repository</a></header>
+<main>
+{{if .Stale}}<section class="warning">The last refresh failed, the packages shown might be outdated.</section>{{end}}
+{{template "content" .}}
+</main>
+</body>
+</html>{{end}}`
+
+const webIndexTemplate = `{{define "title"}}Packages{{end}}
+{{define "content"}}
+<section>
+<h1>Packages</h1>
+<p class="muted">{{.PackageCount}} packages from {{len .Vendors}} vendors{{if not .Updated.IsZero}},
+updated {{formatTime .Updated}}{{end}}</p>
+<nav class="vendors">{{range .Vendors}}<a href="#vendor-{{.Name}}">{{.Name}}</a>{{end}}</nav>
+</section>
+{{range .Vendors}}
+<section id="vendor-{{.Name}}">
+<h2>{{.Name}}</h2>
+<table>
+{{range .Packages}}
+<tr>
+<td><a href="/package/{{.Name}}">{{.Name}}</a>{{if .Degraded}} <span class="tag">degraded</span>{{end}}
+{{- if .Abandoned}} <span class="tag">abandoned</span>{{end}}</td>
+<td>{{.Description}}</td>
+<td class="muted">{{latestVersion .}}</td>
+</tr>
+{{end}}
+</table>
+</section>
+{{else}}
+<section>No packages have been published yet.</section>
+{{end}}
+{{end}}`
+
+const webPackageTemplate = `{{define "title"}}{{.Package.Name}}{{end}}
+{{define "content"}}
+<section>
+<h1>{{.Package.Name}}</h1>
+{{if .Package.Description}}<p>{{.Package.Description}}</p>{{end}}
+<p class="muted">
+{{if .Package.Type}}<span class="tag">{{.Package.Type}}</span>{{end}}
+{{range .Package.License}}<span class="tag">{{.}}</span> {{end}}
+{{if .Package.WebUrl}}<a href="{{.Package.WebUrl}}">{{.Package.Project}}</a>{{else}}{{.Package.Project}}{{end}}
+</p>
+{{if .Package.Abandoned}}<p class="warning">This package is abandoned
+{{- if .Package.Replacement}}, use <a href="/package/{{.Package.Replacement}}">{{.Package.Replacement}}</a> instead
+{{- end}}.</p>{{end}}
+{{if .Package.Degraded}}<p class="warning">The latest changes of this package could not be scanned, the last known good
+version is published instead.</p>{{end}}
+{{if .Published}}
+<h3>Installation</h3>
+<pre>composer config repositories.{{.RepositoryName}} composer {{.RepositoryUrl}}
+composer require {{.Package.Name}}</pre>
+{{else}}<p class="warning">This package is not published, see the outcome of the last scan below.</p>{{end}}
+</section>
+{{with .Scan}}
+<section>
+<h2>Last scan</h2>
+<p><span class="tag">{{.Status}}</span>{{if not .ScannedAt.IsZero}}
+<span class="muted">{{formatTime .ScannedAt}}</span>{{end}}</p>
+{{if .Diagnostics}}
+<table>
+{{range .Diagnostics}}<tr><td><code>{{.Path}}</code></td><td>{{.Message}}</td></tr>
+{{end}}
+</table>
+{{end}}
+</section>
+{{end}}
+{{if .Published}}
+<section>
+<h2>Versions</h2>
+<table>
+{{range .Versions}}
+<tr><td>{{.Version}}</td><td class="muted">{{if not .Released.IsZero}}{{formatTime .Released}}{{end}}</td></tr>
+{{end}}
+</table>
+</section>
+{{if or .Require .RequireDev}}
+<section>
+<h2>Requirements</h2>
+<p class="muted">Taken from the composer.json of the default branch.</p>
+{{if .Require}}<h3>require</h3>{{template "links" .Require}}{{end}}
+{{if .RequireDev}}<h3>require-dev</h3>{{template "links" .RequireDev}}{{end}}
+</section>
+{{end}}
+<section>
+<h2>README</h2>
+{{if .ReadmeError}}<p class="muted">The README could not be loaded from Gitlab.</p>
+{{else if .Readme}}{{.Readme}}
+{{else}}<p class="muted">This package has no README.</p>{{end}}
+</section>
+{{end}}
+{{end}}
+{{define "links"}}
+<table>
+{{range .}}
+<tr>
+<td>{{if .Internal}}<a href="/package/{{.Name}}">{{.Name}}</a>{{else}}{{.Name}}{{end}}</td>
+<td><code>{{.Constraint}}</code></td>
+</tr>
+{{end}}
+</table>
+{{end}}`
@@ -0,0 +1,160 @@
+package stats
+
+import (
+	"encoding/binary"
+	"time"
+
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/atomicptr/gitlab-composer-integration/storage"
+)
+
+var downloadsBucket = []byte("downloads")
+
+// BoltStore keeps the download counts in an embedded database, bolt locks the file exclusively so it can't be
+// shared by multiple instances
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// Open opens (or creates) the statistics database at the given path
+func Open(path string) (*BoltStore, error) {
+	db, err := storage.OpenBoltDatabase(path)
+	if err == storage.ErrDatabaseLocked {
+		return nil, errors.Errorf(
+			"statistics database %s is used by another instance, use the redis backend to share the statistics",
+			path,
+		)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not open statistics database %s", path)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(downloadsBucket)
+		return err
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, errors.Wrap(err, "could not initialize statistics database")
+	}
+
+	return &BoltStore{db: db}, nil
+}
+
+// Close closes the underlying database
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+// RecordAll counts all downloads in a single transaction, this way the database is only synced once
+func (s *BoltStore) RecordAll(downloads []Download, at time.Time) error {
+	if err := validateDownloads(downloads); err != nil {
+		return err
+	}
+
+	if len(downloads) == 0 {
+		return nil
+	}
+
+	day := []byte(at.UTC().Format(dateLayout))
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		for _, download := range downloads {
+			packageBucket, err := tx.Bucket(downloadsBucket).CreateBucketIfNotExists([]byte(download.Name))
+			if err != nil {
+				return err
+			}
+
+			versionBucket, err := packageBucket.CreateBucketIfNotExists([]byte(download.Version))
+			if err != nil {
+				return err
+			}
+
+			err = versionBucket.Put(day, encodeCount(decodeCount(versionBucket.Get(day))+1))
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (s *BoltStore) Package(packageName string, now time.Time) (*PackageStats, bool, error) {
+	var packageStats *PackageStats
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		packageBucket := tx.Bucket(downloadsBucket).Bucket([]byte(packageName))
+		if packageBucket == nil {
+			return nil
+		}
+
+		packageStats = summarize(packageName, collectDailyCounts(packageBucket), now)
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	return packageStats, packageStats != nil, nil
+}
+
+func (s *BoltStore) All(now time.Time) (map[string]Downloads, error) {
+	all := make(map[string]Downloads)
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		root := tx.Bucket(downloadsBucket)
+
+		return root.ForEach(func(name, _ []byte) error {
+			packageBucket := root.Bucket(name)
+			if packageBucket == nil {
+				return nil
+			}
+
+			all[string(name)] = summarize(string(name), collectDailyCounts(packageBucket), now).Downloads
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return all, nil
+}
+
+func collectDailyCounts(packageBucket *bolt.Bucket) []dailyCount {
+	var counts []dailyCount
+
+	_ = packageBucket.ForEach(func(version, _ []byte) error {
+		versionBucket := packageBucket.Bucket(version)
+		if versionBucket == nil {
+			return nil
+		}
+
+		return versionBucket.ForEach(func(day, value []byte) error {
+			counts = append(counts, dailyCount{
+				version: string(version),
+				day:     string(day),
+				count:   decodeCount(value),
+			})
+			return nil
+		})
+	})
+
+	return counts
+}
+
+func encodeCount(count uint64) []byte {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, count)
+	return data
+}
+
+func decodeCount(data []byte) uint64 {
+	if len(data) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(data)
+}
@@ -0,0 +1,103 @@
+package stats
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const dateLayout = "2006-01-02"
+
+// the amount of days (including today) which count towards the monthly downloads
+const monthlyDays = 30
+
+// Downloads contains the download figures in the same form as packagist does
+type Downloads struct {
+	Total   uint64 `json:"total"`
+	Monthly uint64 `json:"monthly"`
+	Daily   uint64 `json:"daily"`
+}
+
+// PackageStats contains the downloads of a package and all of its versions
+type PackageStats struct {
+	Name      string               `json:"name"`
+	Downloads Downloads            `json:"downloads"`
+	Versions  map[string]Downloads `json:"versions"`
+}
+
+// Download is a single download of a package version
+type Download struct {
+	Name    string
+	Version string
+}
+
+// Store persists the download counts per package, version and day
+type Store interface {
+	// RecordAll counts all given downloads at the given time at once, nothing is recorded if any download
+	// has no package name or version
+	RecordAll(downloads []Download, at time.Time) error
+	// Package returns the download statistics of a single package, the second return value is false if there
+	// were no downloads recorded for this package
+	Package(packageName string, now time.Time) (*PackageStats, bool, error)
+	// All returns the download statistics of all known packages
+	All(now time.Time) (map[string]Downloads, error)
+	// Close releases all resources held by the store
+	Close() error
+}
+
+// dailyCount is the amount of downloads of a package version on a single day
+type dailyCount struct {
+	version string
+	day     string
+	count   uint64
+}
+
+func validateDownloads(downloads []Download) error {
+	for _, download := range downloads {
+		if download.Name == "" {
+			return errors.New("can't record download without package name")
+		}
+		if download.Version == "" {
+			return errors.Errorf("can't record download of %s without version", download.Name)
+		}
+	}
+	return nil
+}
+
+// summarize sums up the daily counts of a package to its total, monthly and daily downloads
+func summarize(packageName string, counts []dailyCount, now time.Time) *PackageStats {
+	today := now.UTC().Format(dateLayout)
+	monthStart := now.UTC().AddDate(0, 0, -(monthlyDays - 1)).Format(dateLayout)
+
+	packageStats := &PackageStats{
+		Name:     packageName,
+		Versions: make(map[string]Downloads),
+	}
+
+	for _, c := range counts {
+		var downloads Downloads
+
+		downloads.Total += c.count
+
+		// dates in the ISO format can be compared lexicographically
+		if c.day >= monthStart && c.day <= today {
+			downloads.Monthly += c.count
+		}
+
+		if c.day == today {
+			downloads.Daily += c.count
+		}
+
+		versionDownloads := packageStats.Versions[c.version]
+		versionDownloads.Total += downloads.Total
+		versionDownloads.Monthly += downloads.Monthly
+		versionDownloads.Daily += downloads.Daily
+		packageStats.Versions[c.version] = versionDownloads
+
+		packageStats.Downloads.Total += downloads.Total
+		packageStats.Downloads.Monthly += downloads.Monthly
+		packageStats.Downloads.Daily += downloads.Daily
+	}
+
+	return packageStats
+}
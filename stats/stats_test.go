@@ -0,0 +1,161 @@
+package stats
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func openTestStore(t *testing.T) (Store, func()) {
+	dir, err := ioutil.TempDir("", "gci-stats")
+	assert.Nil(t, err)
+
+	store, err := Open(path.Join(dir, "stats.db"))
+	assert.Nil(t, err)
+
+	return store, func() {
+		_ = store.Close()
+		_ = os.RemoveAll(dir)
+	}
+}
+
+func openTestRedisStore(t *testing.T) (Store, func()) {
+	server, err := miniredis.Run()
+	assert.Nil(t, err)
+
+	store := OpenRedis("redis://" + server.Addr())
+
+	return store, func() {
+		_ = store.Close()
+		server.Close()
+	}
+}
+
+// testStores runs the test against every store
+func testStores(t *testing.T, test func(t *testing.T, store Store)) {
+	stores := map[string]func(t *testing.T) (Store, func()){
+		"bolt":  openTestStore,
+		"redis": openTestRedisStore,
+	}
+
+	for name, open := range stores {
+		t.Run(name, func(t *testing.T) {
+			store, cleanup := open(t)
+			defer cleanup()
+
+			test(t, store)
+		})
+	}
+}
+
+func TestRecordAllWithoutPackageName(t *testing.T) {
+	testStores(t, func(t *testing.T, store Store) {
+		err := store.RecordAll([]Download{{Name: "atomicptr/test", Version: "v1.0.0"}, {Version: "v1.0.0"}}, time.Now())
+		assert.NotNil(t, err)
+
+		// nothing is recorded
+		_, found, err := store.Package("atomicptr/test", time.Now())
+		assert.Nil(t, err)
+		assert.False(t, found)
+	})
+}
+
+func TestRecordAllWithoutVersion(t *testing.T) {
+	testStores(t, func(t *testing.T, store Store) {
+		err := store.RecordAll([]Download{{Name: "atomicptr/test", Version: "v1.0.0"}, {Name: "atomicptr/test"}}, time.Now())
+		assert.NotNil(t, err)
+
+		// both stores record nothing
+		_, found, err := store.Package("atomicptr/test", time.Now())
+		assert.Nil(t, err)
+		assert.False(t, found)
+	})
+}
+
+func TestPackageUnknown(t *testing.T) {
+	testStores(t, func(t *testing.T, store Store) {
+		_, found, err := store.Package("atomicptr/unknown", time.Now())
+		assert.Nil(t, err)
+		assert.False(t, found)
+	})
+}
+
+func TestPackage(t *testing.T) {
+	testStores(t, func(t *testing.T, store Store) {
+		now := time.Date(2020, 3, 31, 12, 0, 0, 0, time.UTC)
+
+		assert.Nil(t, store.RecordAll([]Download{{Name: "atomicptr/test", Version: "v1.0.0"}}, now))
+		assert.Nil(t, store.RecordAll([]Download{{Name: "atomicptr/test", Version: "v1.0.0"}}, now.Add(-time.Hour)))
+		assert.Nil(t, store.RecordAll([]Download{{Name: "atomicptr/test", Version: "v1.0.0"}}, now.AddDate(0, 0, -3)))
+		assert.Nil(t, store.RecordAll([]Download{{Name: "atomicptr/test", Version: "v2.0.0"}}, now.AddDate(0, 0, -29)))
+		assert.Nil(t, store.RecordAll([]Download{{Name: "atomicptr/test", Version: "v2.0.0"}}, now.AddDate(0, 0, -30)))
+		assert.Nil(t, store.RecordAll([]Download{{Name: "atomicptr/other", Version: "v1.0.0"}}, now))
+
+		packageStats, found, err := store.Package("atomicptr/test", now)
+		assert.Nil(t, err)
+		assert.True(t, found)
+
+		assert.EqualValues(t, "atomicptr/test", packageStats.Name)
+		assert.EqualValues(t, Downloads{Total: 5, Monthly: 4, Daily: 2}, packageStats.Downloads)
+		assert.EqualValues(t, Downloads{Total: 3, Monthly: 3, Daily: 2}, packageStats.Versions["v1.0.0"])
+		assert.EqualValues(t, Downloads{Total: 2, Monthly: 1, Daily: 0}, packageStats.Versions["v2.0.0"])
+	})
+}
+
+func TestAll(t *testing.T) {
+	testStores(t, func(t *testing.T, store Store) {
+		now := time.Now()
+
+		assert.Nil(t, store.RecordAll([]Download{
+			{Name: "atomicptr/test", Version: "v1.0.0"},
+			{Name: "atomicptr/test", Version: "dev-master"},
+			{Name: "atomicptr/other", Version: "v1.0.0"},
+		}, now))
+
+		all, err := store.All(now)
+		assert.Nil(t, err)
+
+		assert.Len(t, all, 2)
+		assert.EqualValues(t, 2, all["atomicptr/test"].Total)
+		assert.EqualValues(t, 1, all["atomicptr/other"].Daily)
+	})
+}
+
+func TestOpenLockedDatabase(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gci-stats")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	store, err := Open(path.Join(dir, "stats.db"))
+	assert.Nil(t, err)
+	defer store.Close()
+
+	_, err = Open(path.Join(dir, "stats.db"))
+	assert.NotNil(t, err)
+}
+
+func TestRedisStoreShared(t *testing.T) {
+	server, err := miniredis.Run()
+	assert.Nil(t, err)
+	defer server.Close()
+
+	first := OpenRedis("redis://" + server.Addr())
+	defer first.Close()
+	second := OpenRedis("redis://" + server.Addr())
+	defer second.Close()
+
+	now := time.Now()
+
+	assert.Nil(t, first.RecordAll([]Download{{Name: "atomicptr/test", Version: "v1.0.0"}}, now))
+	assert.Nil(t, second.RecordAll([]Download{{Name: "atomicptr/test", Version: "v1.0.0"}}, now))
+
+	packageStats, found, err := first.Package("atomicptr/test", now)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.EqualValues(t, 2, packageStats.Downloads.Total)
+}
@@ -0,0 +1,213 @@
+package storage
+
+import (
+	"bytes"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+// the maximum time to wait for the file lock of the database, it is only held by another instance
+const boltLockTimeout = time.Second
+
+var cacheBucket = []byte("cache")
+
+// ErrDatabaseLocked is returned if a bolt database is used by another instance
+var ErrDatabaseLocked = errors.New("database is used by another instance")
+
+// BoltStorage keeps all data in an embedded bolt database. The database stays open until the storage is closed
+// and bolt locks the file exclusively, so it can't be shared by multiple instances.
+type BoltStorage struct {
+	path  string
+	mutex sync.Mutex
+	db    *bolt.DB
+}
+
+// NewBolt creates a storage using the bolt database at the given path, it is opened on the first operation
+func NewBolt(path string) *BoltStorage {
+	return &BoltStorage{path: path}
+}
+
+// Open opens the database unless it is open already, fails if the database is used by another instance. Returns
+// an error wrapping ErrIncompatibleFile if the file is no bolt database.
+func (b *BoltStorage) Open() error {
+	_, err := b.open()
+	return err
+}
+
+func (b *BoltStorage) Get(key string) ([]byte, bool, error) {
+	var value []byte
+
+	err := b.view(func(bucket *bolt.Bucket) error {
+		if data := bucket.Get([]byte(key)); data != nil {
+			// data is only valid during the transaction
+			value = append([]byte{}, data...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	return value, value != nil, nil
+}
+
+func (b *BoltStorage) Set(key string, value []byte) error {
+	return b.update(func(bucket *bolt.Bucket) error {
+		return bucket.Put([]byte(key), value)
+	})
+}
+
+func (b *BoltStorage) SetAll(values map[string][]byte) error {
+	return b.update(func(bucket *bolt.Bucket) error {
+		for key, value := range values {
+			if err := bucket.Put([]byte(key), value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (b *BoltStorage) Delete(key string) error {
+	return b.update(func(bucket *bolt.Bucket) error {
+		return bucket.Delete([]byte(key))
+	})
+}
+
+func (b *BoltStorage) Update(key string, update func(value []byte) ([]byte, error)) error {
+	return b.update(func(bucket *bolt.Bucket) error {
+		var value []byte
+		if data := bucket.Get([]byte(key)); data != nil {
+			value = append([]byte{}, data...)
+		}
+
+		updated, err := update(value)
+		if err != nil {
+			return err
+		}
+
+		if updated == nil {
+			return bucket.Delete([]byte(key))
+		}
+		return bucket.Put([]byte(key), updated)
+	})
+}
+
+func (b *BoltStorage) DeletePrefix(prefix string) error {
+	return b.update(func(bucket *bolt.Bucket) error {
+		var keys [][]byte
+
+		prefixBytes := []byte(prefix)
+
+		cursor := bucket.Cursor()
+		for key, _ := cursor.Seek(prefixBytes); key != nil && bytes.HasPrefix(key, prefixBytes); key, _ = cursor.Next() {
+			// keys are only valid during the transaction and must not be modified while iterating
+			keys = append(keys, append([]byte{}, key...))
+		}
+
+		for _, key := range keys {
+			if err := bucket.Delete(key); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (b *BoltStorage) Flush() error {
+	db, err := b.open()
+	if err != nil {
+		return err
+	}
+
+	return db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket(cacheBucket) == nil {
+			return nil
+		}
+		return tx.DeleteBucket(cacheBucket)
+	})
+}
+
+func (b *BoltStorage) Close() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.db == nil {
+		return nil
+	}
+
+	err := b.db.Close()
+	b.db = nil
+	return errors.Wrapf(err, "could not close cache database %s", b.path)
+}
+
+func (b *BoltStorage) view(fn func(bucket *bolt.Bucket) error) error {
+	db, err := b.open()
+	if err != nil {
+		return err
+	}
+
+	return db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(cacheBucket)
+		if bucket == nil {
+			return nil
+		}
+		return fn(bucket)
+	})
+}
+
+func (b *BoltStorage) update(fn func(bucket *bolt.Bucket) error) error {
+	db, err := b.open()
+	if err != nil {
+		return err
+	}
+
+	return db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(cacheBucket)
+		if err != nil {
+			return err
+		}
+		return fn(bucket)
+	})
+}
+
+// open returns the database, it is opened on the first call and kept open until the storage is closed
+func (b *BoltStorage) open() (*bolt.DB, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.db != nil {
+		return b.db, nil
+	}
+
+	db, err := OpenBoltDatabase(b.path)
+	if err == ErrDatabaseLocked {
+		return nil, errors.Errorf(
+			"cache database %s is used by another instance, use the redis backend to share the cache",
+			b.path,
+		)
+	}
+	if err == bolt.ErrInvalid || err == bolt.ErrVersionMismatch || err == bolt.ErrChecksum {
+		return nil, errors.Wrapf(ErrIncompatibleFile, "%s is no cache database (%s)", b.path, err)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not open cache database %s", b.path)
+	}
+
+	b.db = db
+	return db, nil
+}
+
+// OpenBoltDatabase opens (or creates) the bolt database at the given path, returns ErrDatabaseLocked if the
+// database is used by another instance
+func OpenBoltDatabase(path string) (*bolt.DB, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: boltLockTimeout})
+	if err == bolt.ErrTimeout {
+		return nil, ErrDatabaseLocked
+	}
+	return db, err
+}
@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/gob"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// every cache file starts with this magic string followed by the format version and the sha256 checksum
+// of the payload
+const fileMagic = "GCICACHE"
+
+// fileFormatVersion has to be increased whenever the layout of the cached entries changes, a migration
+// from the previous version can be added to fileMigrations
+const fileFormatVersion uint32 = 1
+
+const fileHeaderSize = len(fileMagic) + 4 + sha256.Size
+
+// fileMigrations converts the entries of a cache file with the given (outdated) version to the next version
+var fileMigrations = map[uint32]func(entries map[string][]byte) (map[string][]byte, error){}
+
+// ErrIncompatibleFile is returned if a cache file can't be used, it should be discarded
+var ErrIncompatibleFile = errors.New("incompatible cache file")
+
+// writeFile writes the entries to the given path, the file is written to a temporary file first and only
+// replaces the given path once it is complete. This way a crash never leaves a half-written file behind.
+func writeFile(path string, entries map[string][]byte) error {
+	var payload bytes.Buffer
+	if err := gob.NewEncoder(&payload).Encode(entries); err != nil {
+		return errors.Wrap(err, "could not encode cache entries")
+	}
+
+	checksum := sha256.Sum256(payload.Bytes())
+
+	dir := filepath.Dir(path)
+
+	tempFile, err := ioutil.TempFile(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return errors.Wrap(err, "could not create temporary cache file")
+	}
+	// this is a no-op once the file has been renamed
+	defer os.Remove(tempFile.Name())
+
+	header := make([]byte, 0, fileHeaderSize)
+	header = append(header, fileMagic...)
+	header = append(header, make([]byte, 4)...)
+	binary.BigEndian.PutUint32(header[len(fileMagic):], fileFormatVersion)
+	header = append(header, checksum[:]...)
+
+	for _, data := range [][]byte{header, payload.Bytes()} {
+		if _, err := tempFile.Write(data); err != nil {
+			_ = tempFile.Close()
+			return errors.Wrap(err, "could not write temporary cache file")
+		}
+	}
+
+	if err := tempFile.Sync(); err != nil {
+		_ = tempFile.Close()
+		return errors.Wrap(err, "could not sync temporary cache file")
+	}
+
+	if err := tempFile.Close(); err != nil {
+		return errors.Wrap(err, "could not close temporary cache file")
+	}
+
+	if err := os.Rename(tempFile.Name(), path); err != nil {
+		return errors.Wrap(err, "could not replace cache file")
+	}
+
+	// make sure the rename itself is persisted, not every platform supports syncing directories
+	if dirFile, err := os.Open(dir); err == nil {
+		_ = dirFile.Sync()
+		_ = dirFile.Close()
+	}
+
+	return nil
+}
+
+// readFile reads the entries of the cache file at the given path, files from older versions are migrated
+// if possible. Returns an error wrapping ErrIncompatibleFile if the file can't be used.
+func readFile(path string) (map[string][]byte, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < fileHeaderSize || string(data[:len(fileMagic)]) != fileMagic {
+		return nil, errors.Wrap(ErrIncompatibleFile, "unknown file format (probably written by an older release)")
+	}
+
+	version := binary.BigEndian.Uint32(data[len(fileMagic):])
+	checksum := data[len(fileMagic)+4 : fileHeaderSize]
+	payload := data[fileHeaderSize:]
+
+	if version > fileFormatVersion {
+		return nil, errors.Wrapf(
+			ErrIncompatibleFile,
+			"format version %d is newer than the supported version %d",
+			version,
+			fileFormatVersion,
+		)
+	}
+
+	actualChecksum := sha256.Sum256(payload)
+	if !bytes.Equal(checksum, actualChecksum[:]) {
+		return nil, errors.Wrap(ErrIncompatibleFile, "checksum mismatch (the file is corrupt)")
+	}
+
+	var entries map[string][]byte
+	if err := gob.NewDecoder(bytes.NewReader(payload)).Decode(&entries); err != nil {
+		return nil, errors.Wrapf(ErrIncompatibleFile, "could not decode entries: %s", err)
+	}
+
+	for ; version < fileFormatVersion; version++ {
+		migrate, ok := fileMigrations[version]
+		if !ok {
+			return nil, errors.Wrapf(ErrIncompatibleFile, "can't migrate format version %d", version)
+		}
+
+		entries, err = migrate(entries)
+		if err != nil {
+			return nil, errors.Wrapf(ErrIncompatibleFile, "could not migrate format version %d: %s", version, err)
+		}
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"path"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteAndReadFile(t *testing.T) {
+	dir, cleanup := createTempDir(t)
+	defer cleanup()
+
+	filePath := path.Join(dir, "cache")
+	entries := map[string][]byte{
+		"generation": []byte("42"),
+		"42/index":   []byte(`{"packages": []}`),
+	}
+
+	assert.Nil(t, writeFile(filePath, entries))
+
+	// only the cache file itself should be left
+	files, err := ioutil.ReadDir(dir)
+	assert.Nil(t, err)
+	assert.Len(t, files, 1)
+
+	restored, err := readFile(filePath)
+	assert.Nil(t, err)
+	assert.EqualValues(t, entries, restored)
+}
+
+func TestReadFileLegacyFormat(t *testing.T) {
+	dir, cleanup := createTempDir(t)
+	defer cleanup()
+
+	filePath := path.Join(dir, "cache")
+	assert.Nil(t, ioutil.WriteFile(filePath, []byte("this is an old gob file"), 0600))
+
+	_, err := readFile(filePath)
+	assert.EqualValues(t, ErrIncompatibleFile, errors.Cause(err))
+}
+
+func TestReadFileCorrupt(t *testing.T) {
+	dir, cleanup := createTempDir(t)
+	defer cleanup()
+
+	filePath := path.Join(dir, "cache")
+	assert.Nil(t, writeFile(filePath, map[string][]byte{"key": []byte("value")}))
+
+	data, err := ioutil.ReadFile(filePath)
+	assert.Nil(t, err)
+	assert.Nil(t, ioutil.WriteFile(filePath, data[:len(data)-1], 0600))
+
+	_, err = readFile(filePath)
+	assert.EqualValues(t, ErrIncompatibleFile, errors.Cause(err))
+}
+
+func TestReadFileNewerVersion(t *testing.T) {
+	dir, cleanup := createTempDir(t)
+	defer cleanup()
+
+	filePath := path.Join(dir, "cache")
+	assert.Nil(t, writeFile(filePath, map[string][]byte{"key": []byte("value")}))
+
+	data, err := ioutil.ReadFile(filePath)
+	assert.Nil(t, err)
+	binary.BigEndian.PutUint32(data[len(fileMagic):], fileFormatVersion+1)
+	assert.Nil(t, ioutil.WriteFile(filePath, data, 0600))
+
+	_, err = readFile(filePath)
+	assert.EqualValues(t, ErrIncompatibleFile, errors.Cause(err))
+}
+
+func TestReadFileMissing(t *testing.T) {
+	_, err := readFile("/this/file/does/not/exist")
+	assert.NotNil(t, err)
+	assert.NotEqual(t, ErrIncompatibleFile, errors.Cause(err))
+}
@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/patrickmn/go-cache"
+)
+
+// MemoryStorage keeps all data in memory and can be persisted in a file
+type MemoryStorage struct {
+	cache *cache.Cache
+	// writeMutex makes updates atomic, all writes have to hold it
+	writeMutex sync.Mutex
+}
+
+// NewMemory creates a new empty in memory storage
+func NewMemory() *MemoryStorage {
+	return &MemoryStorage{
+		cache: cache.New(cache.NoExpiration, cache.NoExpiration),
+	}
+}
+
+func (m *MemoryStorage) Get(key string) ([]byte, bool, error) {
+	value, found := m.cache.Get(key)
+	if !found {
+		return nil, false, nil
+	}
+
+	data, ok := value.([]byte)
+	if !ok {
+		return nil, false, nil
+	}
+
+	return data, true, nil
+}
+
+func (m *MemoryStorage) Set(key string, value []byte) error {
+	m.writeMutex.Lock()
+	defer m.writeMutex.Unlock()
+
+	m.cache.Set(key, value, cache.NoExpiration)
+	return nil
+}
+
+func (m *MemoryStorage) SetAll(values map[string][]byte) error {
+	m.writeMutex.Lock()
+	defer m.writeMutex.Unlock()
+
+	for key, value := range values {
+		m.cache.Set(key, value, cache.NoExpiration)
+	}
+	return nil
+}
+
+func (m *MemoryStorage) Delete(key string) error {
+	m.writeMutex.Lock()
+	defer m.writeMutex.Unlock()
+
+	m.cache.Delete(key)
+	return nil
+}
+
+func (m *MemoryStorage) Update(key string, update func(value []byte) ([]byte, error)) error {
+	m.writeMutex.Lock()
+	defer m.writeMutex.Unlock()
+
+	value, _, _ := m.Get(key)
+
+	updated, err := update(value)
+	if err != nil {
+		return err
+	}
+
+	if updated == nil {
+		m.cache.Delete(key)
+	} else {
+		m.cache.Set(key, updated, cache.NoExpiration)
+	}
+
+	return nil
+}
+
+func (m *MemoryStorage) DeletePrefix(prefix string) error {
+	m.writeMutex.Lock()
+	defer m.writeMutex.Unlock()
+
+	for key := range m.cache.Items() {
+		if strings.HasPrefix(key, prefix) {
+			m.cache.Delete(key)
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStorage) Flush() error {
+	m.writeMutex.Lock()
+	defer m.writeMutex.Unlock()
+
+	m.cache.Flush()
+	return nil
+}
+
+func (m *MemoryStorage) Close() error {
+	return nil
+}
+
+func (m *MemoryStorage) SaveFile(path string) error {
+	entries := make(map[string][]byte)
+
+	for key, item := range m.cache.Items() {
+		if data, ok := item.Object.([]byte); ok {
+			entries[key] = data
+		}
+	}
+
+	return writeFile(path, entries)
+}
+
+func (m *MemoryStorage) LoadFile(path string) error {
+	entries, err := readFile(path)
+	if err != nil {
+		return err
+	}
+
+	m.writeMutex.Lock()
+	defer m.writeMutex.Unlock()
+
+	for key, data := range entries {
+		m.cache.Set(key, data, cache.NoExpiration)
+	}
+
+	return nil
+}
@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func startTestRedis(t *testing.T) (*miniredis.Miniredis, string) {
+	server, err := miniredis.Run()
+	assert.Nil(t, err)
+	return server, "redis://" + server.Addr()
+}
+
+func TestRedisStorage(t *testing.T) {
+	server, url := startTestRedis(t)
+	defer server.Close()
+
+	// keys of other applications are not touched
+	assert.Nil(t, server.Set("other-application", "value"))
+
+	testStorage(t, NewRedis(url))
+
+	assert.True(t, server.Exists("other-application"))
+}
+
+func TestRedisStorageSharedBetweenInstances(t *testing.T) {
+	server, url := startTestRedis(t)
+	defer server.Close()
+
+	first := NewRedis(url)
+	second := NewRedis(url)
+	defer first.Close()
+	defer second.Close()
+
+	assert.Nil(t, first.Set("key", []byte("value")))
+
+	value, found, err := second.Get("key")
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.EqualValues(t, "value", string(value))
+}
+
+func TestRedisStorageConcurrentUpdates(t *testing.T) {
+	server, url := startTestRedis(t)
+	defer server.Close()
+
+	first := NewRedis(url)
+	second := NewRedis(url)
+	defer first.Close()
+	defer second.Close()
+
+	testConcurrentUpdates(t, first, second)
+}
+
+func TestRedisStorageLeadership(t *testing.T) {
+	server, url := startTestRedis(t)
+	defer server.Close()
+
+	storage := NewRedis(url)
+	defer storage.Close()
+
+	leader, err := storage.AcquireLeadership("first", time.Minute)
+	assert.Nil(t, err)
+	assert.True(t, leader)
+
+	leader, err = storage.AcquireLeadership("second", time.Minute)
+	assert.Nil(t, err)
+	assert.False(t, leader)
+
+	// the leader can extend its leadership
+	leader, err = storage.AcquireLeadership("first", time.Minute)
+	assert.Nil(t, err)
+	assert.True(t, leader)
+
+	// releasing as another instance does nothing
+	assert.Nil(t, storage.ReleaseLeadership("second"))
+	leader, _ = storage.AcquireLeadership("second", time.Minute)
+	assert.False(t, leader)
+
+	assert.Nil(t, storage.ReleaseLeadership("first"))
+	leader, _ = storage.AcquireLeadership("second", time.Minute)
+	assert.True(t, leader)
+}
+
+func TestRedisStorageLeadershipSurvivesFlush(t *testing.T) {
+	server, url := startTestRedis(t)
+	defer server.Close()
+
+	storage := NewRedis(url)
+	defer storage.Close()
+
+	leader, _ := storage.AcquireLeadership("first", time.Minute)
+	assert.True(t, leader)
+
+	assert.Nil(t, storage.Set("key", []byte("value")))
+	assert.Nil(t, storage.Flush())
+
+	_, found, _ := storage.Get("key")
+	assert.False(t, found)
+
+	leader, _ = storage.AcquireLeadership("second", time.Minute)
+	assert.False(t, leader)
+
+	leader, _ = storage.AcquireLeadership("first", time.Minute)
+	assert.True(t, leader)
+}
+
+func TestRedisStorageLeadershipExpires(t *testing.T) {
+	server, url := startTestRedis(t)
+	defer server.Close()
+
+	storage := NewRedis(url)
+	defer storage.Close()
+
+	leader, _ := storage.AcquireLeadership("first", time.Minute)
+	assert.True(t, leader)
+
+	server.FastForward(2 * time.Minute)
+
+	leader, _ = storage.AcquireLeadership("second", time.Minute)
+	assert.True(t, leader)
+}
+
+func TestEscapeRedisPattern(t *testing.T) {
+	assert.EqualValues(t, `prefix:\*\?\[a\]\\`, escapeRedisPattern(`prefix:*?[a]\`))
+}
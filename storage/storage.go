@@ -0,0 +1,52 @@
+package storage
+
+import "time"
+
+// Storage is the key value store the service keeps its cache in
+type Storage interface {
+	// Get returns the value of the given key, the second return value is false if the key does not exist
+	Get(key string) ([]byte, bool, error)
+	// Set stores the value under the given key
+	Set(key string, value []byte) error
+	// SetAll stores all given values at once, if it fails none of them might have been stored
+	SetAll(values map[string][]byte) error
+	// Delete removes the given key
+	Delete(key string) error
+	// Update atomically replaces the value of the given key with the value returned by update, which receives the
+	// current value (nil if the key does not exist). The key is removed if update returns nil.
+	Update(key string, update func(value []byte) ([]byte, error)) error
+	// DeletePrefix removes all keys starting with the given prefix
+	DeletePrefix(prefix string) error
+	// Flush removes all keys
+	Flush() error
+	// Close releases all resources held by the storage
+	Close() error
+}
+
+// Persister is implemented by storages which keep their data in memory and have to be saved to
+// a file explicitly to survive restarts
+type Persister interface {
+	// SaveFile atomically replaces the file at the given path with the current data
+	SaveFile(path string) error
+	// LoadFile restores the data from the given file, returns an error wrapping ErrIncompatibleFile if
+	// the file can't be used
+	LoadFile(path string) error
+}
+
+// Opener is implemented by storages which can only be used by a single instance, Open fails if another
+// instance is using the storage already
+type Opener interface {
+	// Open acquires the storage for this instance
+	Open() error
+}
+
+// Elector is implemented by storages which can be shared between multiple instances, only the elected
+// leader refreshes the cache while all instances serve it
+type Elector interface {
+	// AcquireLeadership makes the instance with the given id the leader for the given duration if there is
+	// no other leader, a leader can extend its leadership by calling this again. Returns true if the instance
+	// is the leader.
+	AcquireLeadership(instanceId string, ttl time.Duration) (bool, error)
+	// ReleaseLeadership gives up the leadership of the given instance (if it is the leader)
+	ReleaseLeadership(instanceId string) error
+}
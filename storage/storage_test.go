@@ -0,0 +1,189 @@
+package storage
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func testStorage(t *testing.T, storage Storage) {
+	_, found, err := storage.Get("unknown")
+	assert.Nil(t, err)
+	assert.False(t, found)
+
+	assert.Nil(t, storage.Set("a/index", []byte("index")))
+	assert.Nil(t, storage.Set("a/hash", []byte("hash")))
+	assert.Nil(t, storage.Set("b/index", []byte("other index")))
+
+	value, found, err := storage.Get("a/index")
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.EqualValues(t, "index", string(value))
+
+	assert.Nil(t, storage.SetAll(map[string][]byte{"c/index": []byte("batch index"), "c/hash": []byte("batch hash")}))
+	value, found, _ = storage.Get("c/hash")
+	assert.True(t, found)
+	assert.EqualValues(t, "batch hash", string(value))
+
+	assert.Nil(t, storage.Delete("a/hash"))
+	_, found, _ = storage.Get("a/hash")
+	assert.False(t, found)
+
+	appendValue := func(value []byte) ([]byte, error) {
+		return append(value, 'x'), nil
+	}
+	assert.Nil(t, storage.Update("counter", appendValue))
+	assert.Nil(t, storage.Update("counter", appendValue))
+	value, _, _ = storage.Get("counter")
+	assert.EqualValues(t, "xx", string(value))
+
+	assert.Nil(t, storage.Update("counter", func(value []byte) ([]byte, error) {
+		return nil, nil
+	}))
+	_, found, _ = storage.Get("counter")
+	assert.False(t, found)
+
+	assert.NotNil(t, storage.Update("counter", func(value []byte) ([]byte, error) {
+		return nil, errors.New("failed")
+	}))
+
+	assert.Nil(t, storage.DeletePrefix("a/"))
+	_, found, _ = storage.Get("a/index")
+	assert.False(t, found)
+	_, found, _ = storage.Get("b/index")
+	assert.True(t, found)
+
+	assert.Nil(t, storage.Flush())
+	_, found, _ = storage.Get("b/index")
+	assert.False(t, found)
+
+	assert.Nil(t, storage.Close())
+}
+
+// testConcurrentUpdates updates the same key from the given storages at the same time, no update may get lost
+func testConcurrentUpdates(t *testing.T, storages ...Storage) {
+	const updatesPerStorage = 20
+
+	var wg sync.WaitGroup
+
+	for _, storage := range storages {
+		wg.Add(1)
+		go func(storage Storage) {
+			defer wg.Done()
+
+			for i := 0; i < updatesPerStorage; i++ {
+				err := storage.Update("concurrent", func(value []byte) ([]byte, error) {
+					return append(value, 'x'), nil
+				})
+				assert.Nil(t, err)
+			}
+		}(storage)
+	}
+
+	wg.Wait()
+
+	value, _, err := storages[0].Get("concurrent")
+	assert.Nil(t, err)
+	assert.Len(t, value, updatesPerStorage*len(storages))
+}
+
+func createTempDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "gci-storage")
+	assert.Nil(t, err)
+
+	return dir, func() {
+		_ = os.RemoveAll(dir)
+	}
+}
+
+func TestMemoryStorage(t *testing.T) {
+	testStorage(t, NewMemory())
+}
+
+func TestMemoryStorageConcurrentUpdates(t *testing.T) {
+	storage := NewMemory()
+	testConcurrentUpdates(t, storage, storage, storage)
+}
+
+func TestMemoryStoragePersistence(t *testing.T) {
+	dir, cleanup := createTempDir(t)
+	defer cleanup()
+
+	filePath := path.Join(dir, "cache")
+
+	storage := NewMemory()
+	assert.Nil(t, storage.Set("key", []byte("value")))
+	assert.Nil(t, storage.SaveFile(filePath))
+
+	restored := NewMemory()
+	assert.Nil(t, restored.LoadFile(filePath))
+
+	value, found, err := restored.Get("key")
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.EqualValues(t, "value", string(value))
+}
+
+func TestBoltStorage(t *testing.T) {
+	dir, cleanup := createTempDir(t)
+	defer cleanup()
+
+	testStorage(t, NewBolt(path.Join(dir, "cache.db")))
+}
+
+func TestBoltStorageIncompatibleFile(t *testing.T) {
+	dir, cleanup := createTempDir(t)
+	defer cleanup()
+
+	filePath := path.Join(dir, "cache")
+
+	// the cache file written by the memory backend is no bolt database
+	memory := NewMemory()
+	assert.Nil(t, memory.Set("key", []byte("value")))
+	assert.Nil(t, memory.SaveFile(filePath))
+
+	storage := NewBolt(filePath)
+	err := storage.Open()
+	assert.NotNil(t, err)
+	assert.EqualValues(t, ErrIncompatibleFile, errors.Cause(err))
+}
+
+func TestBoltStorageSingleInstance(t *testing.T) {
+	dir, cleanup := createTempDir(t)
+	defer cleanup()
+
+	filePath := path.Join(dir, "cache.db")
+
+	first := NewBolt(filePath)
+	assert.Nil(t, first.Open())
+	assert.Nil(t, first.Set("key", []byte("value")))
+
+	second := NewBolt(filePath)
+	assert.NotNil(t, second.Open())
+
+	assert.Nil(t, first.Close())
+	assert.Nil(t, second.Open())
+	defer second.Close()
+
+	value, found, err := second.Get("key")
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.EqualValues(t, "value", string(value))
+}
+
+func TestBoltStorageConcurrentUpdates(t *testing.T) {
+	dir, cleanup := createTempDir(t)
+	defer cleanup()
+
+	filePath := path.Join(dir, "cache.db")
+
+	storage := NewBolt(filePath)
+	defer storage.Close()
+
+	testConcurrentUpdates(t, storage, storage, storage)
+}